@@ -5,4 +5,6 @@ go 1.14
 require (
 	github.com/manifoldco/promptui v0.7.0
 	github.com/pborman/getopt v0.0.0-20190409184431-ee0cd42419d3
+	github.com/ulikunitz/xz v0.5.8
+	gopkg.in/yaml.v2 v2.3.0
 )
@@ -0,0 +1,293 @@
+package lib
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/ulikunitz/xz"
+)
+
+/* limits on what an archive may make us write */
+const (
+	maxExtractFileSize  = 512 << 20
+	maxExtractTotalSize = 2 << 30
+)
+
+/* magic numbers of the supported archive formats */
+var (
+	magicGzip  = []byte{0x1f, 0x8b}
+	magicXz    = []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}
+	magicBzip2 = []byte("BZh")
+	magicZip   = []byte("PK\x03\x04")
+)
+
+// DetectArchive : the layout of the file at path, from its content, or from its extension when the content says nothing
+func DetectArchive(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	header := make([]byte, 8)
+	n, err := io.ReadFull(f, header)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", err
+	}
+	header = header[:n]
+
+	switch {
+	case bytes.HasPrefix(header, magicGzip):
+		return LayoutTarGz, nil
+	case bytes.HasPrefix(header, magicXz):
+		return LayoutTarXz, nil
+	case bytes.HasPrefix(header, magicBzip2):
+		return LayoutTarBz2, nil
+	case bytes.HasPrefix(header, magicZip):
+		return LayoutZip, nil
+	}
+
+	name := strings.ToLower(path)
+	switch {
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return LayoutTarGz, nil
+	case strings.HasSuffix(name, ".tar.xz"), strings.HasSuffix(name, ".txz"):
+		return LayoutTarXz, nil
+	case strings.HasSuffix(name, ".tar.bz2"), strings.HasSuffix(name, ".tbz2"):
+		return LayoutTarBz2, nil
+	case strings.HasSuffix(name, ".zip"):
+		return LayoutZip, nil
+	}
+
+	/* anything else is taken for the binary itself */
+	return LayoutBinary, nil
+}
+
+// ExtractArchive : extract the files listed in wanted from the archive at path into dest, detecting its layout when it is auto
+//
+// Only regular files are written, with mode 0755, below dest; entries escaping dest, links in
+// place of a wanted file, files over maxExtractFileSize and archives declaring more than
+// maxExtractTotalSize are refused. Every wanted file must be found.
+func ExtractArchive(path string, dest string, layout string, wanted []string) (string, error) {
+	if layout == LayoutAuto || layout == "" {
+		detected, err := DetectArchive(path)
+		if err != nil {
+			return "", err
+		}
+		Log.Verbosef("%s is a %s archive", filepath.Base(path), detected)
+		layout = detected
+	}
+
+	if layout == LayoutBinary {
+		return layout, nil
+	}
+
+	x := newExtractor(dest, wanted)
+	if layout == LayoutZip {
+		if err := x.unzip(path); err != nil {
+			return layout, err
+		}
+		return layout, x.missing()
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return layout, err
+	}
+	defer f.Close()
+
+	var r io.Reader
+	switch layout {
+	case LayoutTarGz:
+		gzr, err := gzip.NewReader(bufio.NewReader(f))
+		if err != nil {
+			return layout, err
+		}
+		defer gzr.Close()
+		r = gzr
+	case LayoutTarXz:
+		xzr, err := xz.NewReader(bufio.NewReader(f))
+		if err != nil {
+			return layout, err
+		}
+		r = xzr
+	case LayoutTarBz2:
+		r = bzip2.NewReader(bufio.NewReader(f))
+	default:
+		return layout, fmt.Errorf("unknown archive layout %q", layout)
+	}
+
+	if err := x.untar(r); err != nil {
+		return layout, fmt.Errorf("%s: %v", filepath.Base(path), err)
+	}
+	return layout, x.missing()
+}
+
+// extractor : what is being extracted where, and how much so far
+type extractor struct {
+	dest    string
+	wanted  map[string]bool
+	found   map[string]bool
+	total   int64
+	entries []string
+}
+
+func newExtractor(dest string, wanted []string) *extractor {
+	x := &extractor{dest: dest, wanted: map[string]bool{}, found: map[string]bool{}}
+	for _, w := range wanted {
+		x.wanted[path.Clean(w)] = true
+		x.entries = append(x.entries, path.Clean(w))
+	}
+	return x
+}
+
+// entry : the cleaned name of an archive entry, and whether it is one to extract
+func (x *extractor) entry(name string, size int64, link bool) (string, bool, error) {
+	clean := path.Clean(strings.Replace(name, "\\", "/", -1))
+	if path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", false, fmt.Errorf("entry %q escapes the extraction dir", name)
+	}
+
+	x.total += size
+	if x.total > maxExtractTotalSize {
+		return "", false, fmt.Errorf("archive holds more than %d bytes", int64(maxExtractTotalSize))
+	}
+
+	if !x.wanted[clean] {
+		return clean, false, nil
+	}
+	if link {
+		return "", false, fmt.Errorf("entry %q is a link, expecting a regular file", name)
+	}
+	if size > maxExtractFileSize {
+		return "", false, fmt.Errorf("entry %q is %d bytes, more than the %d allowed", name, size, int64(maxExtractFileSize))
+	}
+	return clean, true, nil
+}
+
+// write : copy one wanted entry below dest, never through an existing file or link
+func (x *extractor) write(name string, r io.Reader) error {
+	target := filepath.Join(x.dest, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	os.Remove(target)
+
+	Log.Debugf("extract %s", target)
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0755)
+	if err != nil {
+		return err
+	}
+
+	/* the header may lie about the size, read at most one byte over the limit to tell */
+	n, err := io.Copy(f, io.LimitReader(r, maxExtractFileSize+1))
+	if err == nil && n > maxExtractFileSize {
+		err = fmt.Errorf("entry %q is more than the %d bytes allowed", name, int64(maxExtractFileSize))
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(target)
+		return err
+	}
+	x.found[name] = true
+	return nil
+}
+
+// missing : an error naming the wanted files the archive did not hold
+func (x *extractor) missing() error {
+	missing := []string{}
+	for _, w := range x.entries {
+		if !x.found[w] {
+			missing = append(missing, w)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("archive has no %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// untar : extract the wanted files of an uncompressed tar stream
+func (x *extractor) untar(r io.Reader) error {
+	tr := tar.NewReader(r)
+
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		link := header.Typeflag == tar.TypeSymlink || header.Typeflag == tar.TypeLink
+		if header.Typeflag != tar.TypeReg && !link {
+			/* directories are created as needed, devices and fifos never */
+			continue
+		}
+
+		name, extract, err := x.entry(header.Name, header.Size, link)
+		if err != nil {
+			return err
+		}
+		if !extract {
+			Log.Debugf("skip %s", name)
+			continue
+		}
+		if err := x.write(name, tr); err != nil {
+			return err
+		}
+	}
+}
+
+// unzip : extract the wanted files of a zip archive
+func (x *extractor) unzip(path string) error {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+
+	for _, file := range zr.File {
+		mode := file.Mode()
+		if mode.IsDir() {
+			continue
+		}
+		link := mode&os.ModeSymlink != 0
+		if !mode.IsRegular() && !link {
+			continue
+		}
+
+		name, extract, err := x.entry(file.Name, int64(file.UncompressedSize64), link)
+		if err != nil {
+			return fmt.Errorf("%s: %v", filepath.Base(path), err)
+		}
+		if !extract {
+			Log.Debugf("skip %s", name)
+			continue
+		}
+
+		rc, err := file.Open()
+		if err != nil {
+			return err
+		}
+		err = x.write(name, rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,200 @@
+package lib_test
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tokiwong/helm-switcher/lib"
+	"github.com/ulikunitz/xz"
+)
+
+/* linux-amd64/helm containing "helm-bz2", made with tar -cjf */
+const testTarBz2 = "QlpoOTFBWSZTWZrDwOYAAJ17hMqAAEBAAv+ARAB2Z55QAACACCAAlISo0E00E2p6AnqaZMgSVD0mo9TBAaMAm9R9jKXa5iCQFkUJI8U94yBiqCpCwmEgnFMpDgQ6L4jpzgArLOsBxOA8qHC+14GiD0GC/NrHDSSUK6iD4jVGpmLkbmv7konAw+HJQOgoKzoOMDn8cYeIkHIu5IpwoSE1h4HM"
+
+// writeTestTar : a tarball holding linux-amd64/helm with content, compressed by wrap
+func writeTestTar(t *testing.T, path string, content string, wrap func(io.Writer) io.WriteCloser) {
+	var buffer bytes.Buffer
+	w := wrap(&buffer)
+	tw := tar.NewWriter(w)
+	tw.WriteHeader(&tar.Header{Name: "linux-amd64/", Typeflag: tar.TypeDir, Mode: 0755})
+	tw.WriteHeader(&tar.Header{Name: "linux-amd64/helm", Typeflag: tar.TypeReg, Mode: 0755, Size: int64(len(content))})
+	tw.Write([]byte(content))
+	tw.Close()
+	w.Close()
+	if err := ioutil.WriteFile(path, buffer.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestExtractArchive : every supported format is detected from its content and extracted
+func TestExtractArchive(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "helmswitch-archive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	/* names without extension, so only the content tells the format */
+	writeTestTar(t, filepath.Join(dir, "gz"), "helm-gz", func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) })
+	writeTestTar(t, filepath.Join(dir, "xz"), "helm-xz", func(w io.Writer) io.WriteCloser {
+		xw, _ := xz.NewWriter(w)
+		return xw
+	})
+	bz2, _ := base64.StdEncoding.DecodeString(testTarBz2)
+	ioutil.WriteFile(filepath.Join(dir, "bz2"), bz2, 0644)
+
+	var buffer bytes.Buffer
+	zw := zip.NewWriter(&buffer)
+	f, _ := zw.Create("linux-amd64/helm")
+	f.Write([]byte("helm-zip"))
+	zw.Close()
+	ioutil.WriteFile(filepath.Join(dir, "zip"), buffer.Bytes(), 0644)
+
+	ioutil.WriteFile(filepath.Join(dir, "raw"), []byte("\x7fELF helm"), 0755)
+
+	cases := map[string]string{
+		"gz":  lib.LayoutTarGz,
+		"xz":  lib.LayoutTarXz,
+		"bz2": lib.LayoutTarBz2,
+		"zip": lib.LayoutZip,
+		"raw": lib.LayoutBinary,
+	}
+	for name, expected := range cases {
+		dest := filepath.Join(dir, "out-"+name)
+		os.MkdirAll(dest, 0755)
+
+		layout, err := lib.ExtractArchive(filepath.Join(dir, name), dest, lib.LayoutAuto, []string{"linux-amd64/helm"})
+		if err != nil || layout != expected {
+			t.Errorf("%s: unexpected layout %q %v [unexpected]", name, layout, err)
+			continue
+		}
+		if layout == lib.LayoutBinary {
+			t.Logf("%s left as is [expected]", name)
+			continue
+		}
+		if content, _ := ioutil.ReadFile(filepath.Join(dest, "linux-amd64", "helm")); string(content) == "helm-"+name {
+			t.Logf("%s extracted [expected]", name)
+		} else {
+			t.Errorf("%s: unexpected content %q [unexpected]", name, content)
+		}
+	}
+}
+
+// TestDetectArchiveExtension : the extension decides when the content is not a known archive
+func TestDetectArchiveExtension(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "helmswitch-archive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "helm-v3.3.0-linux-amd64.zip")
+	ioutil.WriteFile(path, []byte("not really"), 0644)
+
+	if layout, _ := lib.DetectArchive(path); layout == lib.LayoutZip {
+		t.Log("Layout from extension [expected]")
+	} else {
+		t.Errorf("Unexpected layout %q [unexpected]", layout)
+	}
+}
+
+// writeTarEntries : a gzip tarball made of the given headers, regular files get their name as content
+func writeTarEntries(t *testing.T, path string, headers []tar.Header) {
+	var buffer bytes.Buffer
+	gw := gzip.NewWriter(&buffer)
+	tw := tar.NewWriter(gw)
+	for _, h := range headers {
+		h := h
+		if h.Typeflag == tar.TypeReg {
+			h.Size = int64(len(h.Name))
+		}
+		tw.WriteHeader(&h)
+		if h.Typeflag == tar.TypeReg {
+			tw.Write([]byte(h.Name))
+		}
+	}
+	tw.Close()
+	gw.Close()
+	if err := ioutil.WriteFile(path, buffer.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestExtractArchiveHardened : traversal, links and missing binaries are refused, other files are left out
+func TestExtractArchiveHardened(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "helmswitch-archive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	wanted := []string{"linux-amd64/helm"}
+
+	cases := map[string][]tar.Header{
+		"traversal": {
+			{Name: "../../evil", Typeflag: tar.TypeReg, Mode: 0755},
+			{Name: "linux-amd64/helm", Typeflag: tar.TypeReg, Mode: 0755},
+		},
+		"absolute": {
+			{Name: "/tmp/evil", Typeflag: tar.TypeReg, Mode: 0755},
+		},
+		"symlink": {
+			{Name: "linux-amd64/helm", Typeflag: tar.TypeSymlink, Linkname: "/etc/passwd"},
+		},
+		"hardlink": {
+			{Name: "linux-amd64/helm", Typeflag: tar.TypeLink, Linkname: "../../etc/passwd"},
+		},
+		"missing": {
+			{Name: "linux-amd64/tiller", Typeflag: tar.TypeReg, Mode: 0755},
+		},
+	}
+	for name, headers := range cases {
+		archive := filepath.Join(dir, name+".tar.gz")
+		writeTarEntries(t, archive, headers)
+		dest := filepath.Join(dir, "out-"+name)
+		os.MkdirAll(dest, 0755)
+
+		if _, err := lib.ExtractArchive(archive, dest, lib.LayoutTarGz, wanted); err != nil {
+			t.Logf("%s refused: %v [expected]", name, err)
+		} else {
+			t.Errorf("%s accepted [unexpected]", name)
+		}
+	}
+	if checkFileExist(filepath.Join(dir, "evil")) {
+		t.Error("Entry written outside the extraction dir [unexpected]")
+	}
+
+	archive := filepath.Join(dir, "extra.tar.gz")
+	writeTarEntries(t, archive, []tar.Header{
+		{Name: "linux-amd64/LICENSE", Typeflag: tar.TypeReg, Mode: 0644},
+		{Name: "linux-amd64/helm", Typeflag: tar.TypeReg, Mode: 04777},
+		{Name: "linux-amd64/link", Typeflag: tar.TypeSymlink, Linkname: "/etc/passwd"},
+	})
+	dest := filepath.Join(dir, "out-extra")
+	os.MkdirAll(dest, 0755)
+	if _, err := lib.ExtractArchive(archive, dest, lib.LayoutTarGz, wanted); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(filepath.Join(dest, "linux-amd64", "helm"))
+	if err == nil && info.Mode().Perm() == 0755 && info.Mode()&os.ModeSetuid == 0 {
+		t.Log("Binary extracted with mode 0755 [expected]")
+	} else {
+		t.Errorf("Unexpected binary %v %v [unexpected]", info, err)
+	}
+	if !checkFileExist(filepath.Join(dest, "linux-amd64", "LICENSE")) && !checkFileExist(filepath.Join(dest, "linux-amd64", "link")) {
+		t.Log("Other entries left out [expected]")
+	} else {
+		t.Error("Unexpected entries extracted [unexpected]")
+	}
+}
@@ -0,0 +1,163 @@
+package lib
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+const (
+	chartFile = "Chart.yaml"
+
+	/* how deep below the current dir charts are looked for */
+	chartSearchDepth = 3
+)
+
+// Chart : a Chart.yaml and its apiVersion, v1 works with Helm 2 and 3, v2 requires Helm 3
+type Chart struct {
+	Path       string `json:"path"`
+	APIVersion string `json:"api_version"`
+}
+
+// ChartInference : what the charts of the tree tell about the helm version to use
+type ChartInference struct {
+	Charts []Chart `json:"charts"`
+	/* nil when every version works */
+	Constraint *Constraint `json:"-"`
+	Reason     string      `json:"reason"`
+}
+
+// FindCharts : the nearest Chart.yaml from dir up to the root, else the charts below dir
+func FindCharts(dir string) ([]Chart, error) {
+	for current := dir; ; {
+		path := filepath.Join(current, chartFile)
+		if CheckFileExist(path) {
+			chart, err := readChart(path)
+			if err != nil {
+				return nil, err
+			}
+			return []Chart{chart}, nil
+		}
+		parent := filepath.Dir(current)
+		if parent == current {
+			break
+		}
+		current = parent
+	}
+
+	charts := []Chart{}
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		rel, _ := filepath.Rel(dir, path)
+		if info.IsDir() {
+			depth := len(strings.Split(rel, string(os.PathSeparator)))
+			if rel != "." && (strings.HasPrefix(info.Name(), ".") || info.Name() == "node_modules" || depth > chartSearchDepth) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.Name() != chartFile {
+			return nil
+		}
+		chart, err := readChart(path)
+		if err != nil {
+			Report.Warn("%v", err)
+			return nil
+		}
+		charts = append(charts, chart)
+		/* the charts/ of a chart are its dependencies */
+		return filepath.SkipDir
+	})
+	return charts, err
+}
+
+// readChart : the apiVersion of a Chart.yaml, v1 when it is not set as Helm 2 charts do
+func readChart(path string) (Chart, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Chart{}, err
+	}
+	var metadata struct {
+		APIVersion string `yaml:"apiVersion"`
+	}
+	if err := yaml.Unmarshal(content, &metadata); err != nil {
+		return Chart{}, fmt.Errorf("%s: %v", path, err)
+	}
+	if metadata.APIVersion == "" {
+		metadata.APIVersion = "v1"
+	}
+	Log.Debugf("%s has apiVersion %s", path, metadata.APIVersion)
+	return Chart{Path: path, APIVersion: metadata.APIVersion}, nil
+}
+
+// InferFromCharts : the helm versions the charts of dir work with, false when there is no chart
+func InferFromCharts(dir string) (ChartInference, bool) {
+	charts, err := FindCharts(dir)
+	if err != nil {
+		Report.Warn("%v", err)
+	}
+	if len(charts) == 0 {
+		return ChartInference{}, false
+	}
+
+	inference := ChartInference{Charts: charts}
+	for _, chart := range charts {
+		if chart.APIVersion == "v1" {
+			continue
+		}
+		inference.Constraint, _ = ParseConstraint(">= 3.0.0")
+		inference.Reason = fmt.Sprintf("%s has apiVersion %s, which requires Helm 3", relativePath(dir, chart.Path), chart.APIVersion)
+		return inference, true
+	}
+
+	if len(charts) == 1 {
+		inference.Reason = fmt.Sprintf("%s has apiVersion v1, which works with Helm 2 and Helm 3", relativePath(dir, charts[0].Path))
+	} else {
+		inference.Reason = fmt.Sprintf("%d charts have apiVersion v1, which works with Helm 2 and Helm 3", len(charts))
+	}
+	return inference, true
+}
+
+// Allows : whether the charts work with version
+func (c ChartInference) Allows(version string) bool {
+	return c.Constraint == nil || c.Constraint.Check(version)
+}
+
+// Resolve : active if the charts allow it, else the newest installed version they allow;
+// false when they allow any version or none is installed, leaving the choice to the user
+func (c ChartInference) Resolve(active string, installed []string) (string, bool) {
+	if c.Constraint == nil {
+		return "", false
+	}
+	if active != "" && c.Allows(active) {
+		return active, true
+	}
+	var newest *Version
+	for _, v := range installed {
+		sv, err := NewVersion(v)
+		if err != nil || !c.Allows(v) {
+			continue
+		}
+		if newest == nil || newest.LessThan(*sv) {
+			newest = sv
+		}
+	}
+	if newest == nil {
+		return "", false
+	}
+	return newest.String(), true
+}
+
+// relativePath : path relative to dir when it is below it
+func relativePath(dir string, path string) string {
+	if rel, err := filepath.Rel(dir, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	return path
+}
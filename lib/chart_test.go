@@ -0,0 +1,105 @@
+package lib_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tokiwong/helm-switcher/lib"
+)
+
+// writeChart : a Chart.yaml with apiVersion in dir
+func writeChart(t *testing.T, dir string, apiVersion string) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	content := "name: app\nversion: 0.1.0\n"
+	if apiVersion != "" {
+		content = "apiVersion: " + apiVersion + "\n" + content
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "Chart.yaml"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestInferFromCharts : v2 charts require Helm 3, v1 charts work with both
+func TestInferFromCharts(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "helmswitch-chart")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if _, ok := lib.InferFromCharts(dir); !ok {
+		t.Log("No chart [expected]")
+	} else {
+		t.Error("Chart found in an empty dir [unexpected]")
+	}
+
+	/* a v1 chart with a v2 dependency, and a v1 chart without apiVersion */
+	writeChart(t, filepath.Join(dir, "charts", "legacy"), "v1")
+	writeChart(t, filepath.Join(dir, "charts", "legacy", "charts", "dep"), "v2")
+	writeChart(t, filepath.Join(dir, "charts", "old"), "")
+
+	inference, ok := lib.InferFromCharts(dir)
+	if ok && len(inference.Charts) == 2 && inference.Constraint == nil && inference.Allows("2.16.9") {
+		t.Logf("%s [expected]", inference.Reason)
+	} else {
+		t.Errorf("Unexpected inference %+v [unexpected]", inference)
+	}
+	if _, ok := inference.Resolve("2.16.9", []string{"3.3.0"}); !ok {
+		t.Log("Ambiguous [expected]")
+	} else {
+		t.Error("v1 charts resolved a version [unexpected]")
+	}
+
+	writeChart(t, filepath.Join(dir, "charts", "app"), "v2")
+	inference, ok = lib.InferFromCharts(dir)
+	if ok && !inference.Allows("2.16.9") && inference.Allows("3.3.0") {
+		t.Logf("%s [expected]", inference.Reason)
+	} else {
+		t.Errorf("Unexpected inference %+v [unexpected]", inference)
+	}
+
+	/* inside a chart, the nearest Chart.yaml decides */
+	nested := filepath.Join(dir, "charts", "legacy", "templates")
+	os.MkdirAll(nested, 0755)
+	if inference, ok := lib.InferFromCharts(nested); ok && len(inference.Charts) == 1 && inference.Constraint == nil {
+		t.Logf("%s [expected]", inference.Reason)
+	} else {
+		t.Errorf("Unexpected inference %+v [unexpected]", inference)
+	}
+}
+
+// TestChartResolve : the active version if allowed, else the newest installed allowed one
+func TestChartResolve(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "helmswitch-chart")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writeChart(t, dir, "v2")
+	inference, _ := lib.InferFromCharts(dir)
+
+	cases := []struct {
+		active    string
+		installed []string
+		version   string
+		ok        bool
+	}{
+		{"3.2.4", []string{"3.3.0", "3.2.4"}, "3.2.4", true},
+		{"2.16.9", []string{"2.16.9", "3.1.0", "3.3.0"}, "3.3.0", true},
+		{"2.16.9", []string{"2.16.9"}, "", false},
+	}
+	for _, c := range cases {
+		version, ok := inference.Resolve(c.active, c.installed)
+		if version == c.version && ok == c.ok {
+			t.Logf("active %s: %q [expected]", c.active, version)
+		} else {
+			t.Errorf("Unexpected %q %v for active %s [unexpected]", version, ok, c.active)
+		}
+	}
+}
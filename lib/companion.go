@@ -0,0 +1,80 @@
+package lib
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+)
+
+// Companions : binaries shipped next to the tool by the versions matching their constraint, eg. tiller by Helm 2
+func (t *Tool) Companions(version string) []string {
+	names := []string{}
+	for name, versions := range t.CompanionVersions {
+		constraint, err := ParseConstraint(versions)
+		if err != nil || !constraint.Check(version) {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CompanionPath : path of a companion in the archive, next to the binary of the tool
+func CompanionPath(binPath string, name string) string {
+	return path.Join(path.Dir(binPath), name)
+}
+
+// CompanionBinary : where a companion of version is stored in dir, eg. tiller_2.16.9
+func CompanionBinary(dir string, name string, version string) string {
+	return filepath.Join(dir, name+"_"+version)
+}
+
+// LinkCompanions : point the companion symlinks next to binPath at the binaries of version,
+// removing the managed ones version does not ship
+func LinkCompanions(dir string, version string, binPath string) error {
+	shipped := map[string]bool{}
+	for _, name := range activeTool.Companions(version) {
+		shipped[name] = true
+	}
+
+	names := []string{}
+	for name := range activeTool.CompanionVersions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		link := filepath.Join(filepath.Dir(binPath), name)
+		binary := CompanionBinary(dir, name, version)
+
+		if _, err := os.Lstat(link); err == nil && !isManagedLink(link, dir) {
+			/* never replace a binary the user installed */
+			if shipped[name] {
+				Report.Warn("%s is not a symlink managed by helmswitch, leaving it alone", link)
+			}
+			continue
+		}
+		if CheckSymlink(link) {
+			Log.Debugf("remove symlink %s", link)
+			if err := os.Remove(link); err != nil {
+				return err
+			}
+		}
+
+		if !shipped[name] {
+			continue
+		}
+		if !CheckFileExist(binary) {
+			return fmt.Errorf("%s is missing, reinstall %s %s to get %s", binary, activeTool.Name, version, name)
+		}
+		Log.Debugf("symlink %s -> %s", link, binary)
+		if err := os.Symlink(binary, link); err != nil {
+			return err
+		}
+		Log.Infof("Switched %s to version %q ", name, version)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package lib_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tokiwong/helm-switcher/lib"
+)
+
+// TestCompanions : tiller comes with Helm 2 only
+func TestCompanions(t *testing.T) {
+
+	helm, _ := lib.LookupTool("helm")
+
+	if companions := helm.Companions("2.16.9"); len(companions) == 1 && companions[0] == "tiller" {
+		t.Log("Helm 2 ships tiller [expected]")
+	} else {
+		t.Errorf("Unexpected companions %v [unexpected]", companions)
+	}
+	if companions := helm.Companions("3.3.0"); len(companions) == 0 {
+		t.Log("Helm 3 ships no tiller [expected]")
+	} else {
+		t.Errorf("Unexpected companions %v [unexpected]", companions)
+	}
+	if path := lib.CompanionPath("linux-amd64/helm", "tiller"); path == "linux-amd64/tiller" {
+		t.Log("Companion next to the binary [expected]")
+	} else {
+		t.Errorf("Unexpected path %s [unexpected]", path)
+	}
+}
+
+// TestLinkCompanions : the tiller symlink follows the active Helm 2 version and goes away with Helm 3
+func TestLinkCompanions(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "helmswitch-companion")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store := filepath.Join(dir, "store") + string(os.PathSeparator)
+	bin := filepath.Join(dir, "bin")
+	os.MkdirAll(store, 0755)
+	os.MkdirAll(bin, 0755)
+	for _, name := range []string{"helm_2.16.9", "tiller_2.16.9", "helm_2.17.0", "tiller_2.17.0", "helm_3.3.0"} {
+		ioutil.WriteFile(filepath.Join(store, name), []byte(name), 0755)
+	}
+	binPath := filepath.Join(bin, "helm")
+	tiller := filepath.Join(bin, "tiller")
+
+	for _, version := range []string{"2.16.9", "2.17.0"} {
+		if err := lib.LinkCompanions(store, version, binPath); err != nil {
+			t.Fatal(err)
+		}
+		if target, _ := os.Readlink(tiller); target == lib.CompanionBinary(store, "tiller", version) {
+			t.Logf("tiller -> %s [expected]", target)
+		} else {
+			t.Errorf("Unexpected tiller target %q [unexpected]", target)
+		}
+	}
+
+	if err := lib.LinkCompanions(store, "3.3.0", binPath); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Lstat(tiller); os.IsNotExist(err) {
+		t.Log("tiller removed for Helm 3 [expected]")
+	} else {
+		t.Error("tiller still linked for Helm 3 [unexpected]")
+	}
+
+	/* a tiller installed by other means is left alone */
+	ioutil.WriteFile(tiller, []byte("mine"), 0755)
+	lib.LinkCompanions(store, "2.16.9", binPath)
+	if content, _ := ioutil.ReadFile(tiller); string(content) == "mine" {
+		t.Log("Unmanaged tiller kept [expected]")
+	} else {
+		t.Error("Unmanaged tiller replaced [unexpected]")
+	}
+
+	/* a Helm 2 version installed without its tiller */
+	os.Remove(tiller)
+	os.Remove(filepath.Join(store, "tiller_2.17.0"))
+	if err := lib.LinkCompanions(store, "2.17.0", binPath); err != nil {
+		t.Logf("Missing tiller: %v [expected]", err)
+	} else {
+		t.Error("Missing tiller not reported [unexpected]")
+	}
+}
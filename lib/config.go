@@ -0,0 +1,89 @@
+package lib
+
+import (
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path/filepath"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+const configFile = "config.yaml"
+
+// Config : user settings read from config.yaml in the config dir
+type Config struct {
+	History HistoryConfig `yaml:"history"`
+	Policy  string        `yaml:"policy"`
+
+	/* none, major or version: how the helm homes are shared between versions run by exec */
+	Isolation string `yaml:"isolation"`
+
+	/* helm plugins installed after a switch, completed by those of .helmswitch.yaml */
+	Plugins []Plugin `yaml:"plugins"`
+
+	/* versions to run with the kube contexts matching a pattern, the first matching rule wins */
+	Contexts []ContextRule `yaml:"contexts"`
+}
+
+// HistoryConfig : how many switches are kept and how many recent versions the menu shows
+type HistoryConfig struct {
+	Size   int `yaml:"size"`
+	Recent int `yaml:"recent"`
+}
+
+// DefaultConfig : settings used when config.yaml is missing or leaves a value out
+func DefaultConfig() *Config {
+	return &Config{
+		History: HistoryConfig{
+			Size:   200,
+			Recent: 5,
+		},
+		Isolation: IsolationNone,
+	}
+}
+
+// ConfigDir : $XDG_CONFIG_HOME/helmswitch, ~/.config/helmswitch by default
+func ConfigDir() string {
+	if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+		return filepath.Join(xdg, "helmswitch")
+	}
+	usr, err := user.Current()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(usr.HomeDir, ".config", "helmswitch")
+}
+
+// LoadConfig : read config.yaml from dir, a missing file gives the defaults
+func LoadConfig(dir string) (*Config, error) {
+	cfg := DefaultConfig()
+
+	content, err := ioutil.ReadFile(filepath.Join(dir, configFile))
+	if os.IsNotExist(err) {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, err
+	}
+
+	Log.Debugf("read %s", filepath.Join(dir, configFile))
+	if err := yaml.Unmarshal(content, cfg); err != nil {
+		return DefaultConfig(), err
+	}
+
+	defaults := DefaultConfig()
+	if cfg.History.Size <= 0 {
+		cfg.History.Size = defaults.History.Size
+	}
+	if cfg.History.Recent <= 0 {
+		cfg.History.Recent = defaults.History.Recent
+	}
+	if _, err := HomeDir("", "1.0.0", cfg.Isolation); err != nil {
+		return DefaultConfig(), err
+	}
+	if err := ParseContextRules(cfg.Contexts); err != nil {
+		return DefaultConfig(), err
+	}
+	return cfg, nil
+}
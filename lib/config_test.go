@@ -0,0 +1,41 @@
+package lib_test
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/tokiwong/helm-switcher/lib"
+)
+
+// TestLoadConfig : values from config.yaml override the defaults, missing ones keep them
+func TestLoadConfig(t *testing.T) {
+
+	configDir, err := ioutil.TempDir("", "helmswitch-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(configDir)
+
+	cfg, err := lib.LoadConfig(configDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.History.Size == lib.DefaultConfig().History.Size {
+		t.Log("Defaults used without config.yaml [expected]")
+	} else {
+		t.Errorf("Unexpected history size %v [unexpected]", cfg.History.Size)
+	}
+
+	ioutil.WriteFile(configDir+"/config.yaml", []byte("history:\n  size: 10\n"), 0644)
+
+	cfg, err = lib.LoadConfig(configDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.History.Size == 10 && cfg.History.Recent == lib.DefaultConfig().History.Recent {
+		t.Log("History size read from config.yaml [expected]")
+	} else {
+		t.Errorf("Unexpected history config %+v [unexpected]", cfg.History)
+	}
+}
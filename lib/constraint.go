@@ -0,0 +1,137 @@
+package lib
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	constraintOperatorRegex = regexp.MustCompile(`(>=|<=|!=|>|<|=)\s+`)
+	constraintTermRegex     = regexp.MustCompile(`^(>=|<=|!=|>|<|=)?v?(\d+|x|X|\*)(?:\.(\d+|x|X|\*))?(?:\.(\d+|x|X|\*))?$`)
+)
+
+// Constraint : set of versions such as "< 3.0.0", ">= 3.2.0 < 3.2.4", "2.x || 3.0.x"
+//
+// Alternatives are separated by ||, the terms of an alternative, separated by spaces or
+// commas, must all match. A version without operator, or with = or !=, may leave out
+// parts or use x for them; the comparison operators fill missing parts with 0.
+type Constraint struct {
+	raw          string
+	alternatives [][]constraintTerm
+}
+
+type constraintTerm struct {
+	op      string
+	version Version
+	parts   int
+}
+
+// ParseConstraint : parse a version constraint
+func ParseConstraint(s string) (*Constraint, error) {
+	c := &Constraint{raw: strings.TrimSpace(s)}
+	if c.raw == "" {
+		return nil, fmt.Errorf("empty version constraint")
+	}
+
+	for _, alternative := range strings.Split(c.raw, "||") {
+		alternative = constraintOperatorRegex.ReplaceAllString(alternative, "$1")
+		terms := []constraintTerm{}
+		for _, field := range strings.FieldsFunc(alternative, func(r rune) bool { return r == ' ' || r == ',' || r == '\t' }) {
+			term, err := parseConstraintTerm(field)
+			if err != nil {
+				return nil, fmt.Errorf("invalid version constraint %q: %v", c.raw, err)
+			}
+			terms = append(terms, term)
+		}
+		if len(terms) == 0 {
+			return nil, fmt.Errorf("invalid version constraint %q: empty alternative", c.raw)
+		}
+		c.alternatives = append(c.alternatives, terms)
+	}
+	return c, nil
+}
+
+// parseConstraintTerm : an operator and a possibly partial version
+func parseConstraintTerm(s string) (constraintTerm, error) {
+	m := constraintTermRegex.FindStringSubmatch(s)
+	if m == nil {
+		return constraintTerm{}, fmt.Errorf("%q is not a version", s)
+	}
+
+	term := constraintTerm{op: m[1]}
+	if term.op == "" {
+		term.op = "="
+	}
+
+	parsed := []int64{0, 0, 0}
+	wildcard := false
+	for i, part := range m[2:5] {
+		if part == "" || part == "x" || part == "X" || part == "*" {
+			wildcard = true
+			continue
+		}
+		if wildcard {
+			return constraintTerm{}, fmt.Errorf("%q has a number after a wildcard", s)
+		}
+		parsed[i], _ = strconv.ParseInt(part, 10, 64)
+		term.parts = i + 1
+	}
+	term.version = Version{Major: parsed[0], Minor: parsed[1], Patch: parsed[2]}
+	return term, nil
+}
+
+// Check : whether version satisfies the constraint
+func (c *Constraint) Check(version string) bool {
+	v, err := NewVersion(version)
+	if err != nil {
+		return false
+	}
+
+	for _, terms := range c.alternatives {
+		matched := true
+		for _, term := range terms {
+			if !term.check(v) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// check : whether v satisfies the term
+func (t constraintTerm) check(v *Version) bool {
+	switch t.op {
+	case "=", "!=":
+		equal := true
+		for i := 0; i < t.parts; i++ {
+			if v.Slice()[i] != t.version.Slice()[i] {
+				equal = false
+			}
+		}
+		/* a partial version matches every release of the series, a complete one only the release itself */
+		if t.parts == 3 && v.PreRelease != "" {
+			equal = false
+		}
+		return equal == (t.op == "=")
+	case ">":
+		return v.Compare(t.version) > 0
+	case ">=":
+		return v.Compare(t.version) >= 0
+	case "<":
+		return v.Compare(t.version) < 0
+	case "<=":
+		return v.Compare(t.version) <= 0
+	}
+	return false
+}
+
+// String : the constraint as written
+func (c *Constraint) String() string {
+	return c.raw
+}
@@ -0,0 +1,57 @@
+package lib_test
+
+import (
+	"testing"
+
+	"github.com/tokiwong/helm-switcher/lib"
+)
+
+// TestConstraintCheck : operators, wildcards, partial versions and alternatives
+func TestConstraintCheck(t *testing.T) {
+
+	cases := []struct {
+		constraint string
+		version    string
+		expected   bool
+	}{
+		{"< 3.0.0", "2.16.1", true},
+		{"< 3.0.0", "3.0.0", false},
+		{">= 3.2.0 < 3.2.4", "3.2.3", true},
+		{">=3.2.0, <3.2.4", "3.2.4", false},
+		{"2.x", "2.16.1", true},
+		{"2.x", "3.0.0", false},
+		{"3.2", "3.2.4", true},
+		{"3.2.*", "3.3.0", false},
+		{"3.1.x || 3.2.0", "3.2.0", true},
+		{"3.1.x || 3.2.0", "3.2.1", false},
+		{"v3.3.0", "3.3.0", true},
+		{"!= 3.3.0", "3.3.1", true},
+		{"> 2", "2.16.1", true},
+		{"< 3.0.0", "not-a-version", false},
+	}
+
+	for _, c := range cases {
+		constraint, err := lib.ParseConstraint(c.constraint)
+		if err != nil {
+			t.Errorf("Unable to parse %q: %v [unexpected]", c.constraint, err)
+			continue
+		}
+		if constraint.Check(c.version) == c.expected {
+			t.Logf("%q on %s is %v [expected]", c.constraint, c.version, c.expected)
+		} else {
+			t.Errorf("%q on %s should be %v [unexpected]", c.constraint, c.version, c.expected)
+		}
+	}
+}
+
+// TestParseConstraintInvalid : malformed constraints are refused
+func TestParseConstraintInvalid(t *testing.T) {
+
+	for _, s := range []string{"", "3.x.1", "~> 3.0", "3.0.0 ||", "latest"} {
+		if _, err := lib.ParseConstraint(s); err != nil {
+			t.Logf("%q refused: %v [expected]", s, err)
+		} else {
+			t.Errorf("%q accepted [unexpected]", s)
+		}
+	}
+}
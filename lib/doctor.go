@@ -0,0 +1,393 @@
+package lib
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const githubRateLimitURL = "https://api.github.com/rate_limit"
+
+// Status of a doctor check
+const (
+	CheckOK   = "ok"
+	CheckWarn = "warn"
+	CheckFail = "fail"
+)
+
+// Check : outcome of a single doctor check
+type Check struct {
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	Message    string `json:"message"`
+	Suggestion string `json:"suggestion,omitempty"`
+	Fixed      bool   `json:"fixed"`
+
+	fix func() error
+}
+
+// DoctorOptions : what the doctor should inspect
+type DoctorOptions struct {
+	InstallDir   string
+	BinPath      string
+	RateLimitURL string
+	Fix          bool
+}
+
+// DefaultDoctorOptions : inspect the default store and the given binary path
+func DefaultDoctorOptions(binPath string) DoctorOptions {
+	return DoctorOptions{
+		InstallDir:   installLocation,
+		BinPath:      binPath,
+		RateLimitURL: githubRateLimitURL,
+	}
+}
+
+// Doctor : diagnose the helmswitch setup, applying the available fixes when opts.Fix is set
+func Doctor(opts DoctorOptions) []Check {
+
+	checks := []Check{
+		checkInstallDir(opts.InstallDir),
+		checkBinDir(opts.BinPath),
+		checkBinDirInPath(opts.BinPath),
+		checkHelmInPath(opts.BinPath, opts.InstallDir),
+		checkDanglingSymlinks(opts.BinPath, opts.InstallDir),
+		checkOrphans(opts.InstallDir),
+		checkHistory(opts.InstallDir),
+		checkState(opts.InstallDir),
+		checkGitHub(opts.RateLimitURL),
+	}
+	checks = append(checks, checkBinaries(opts.InstallDir)...)
+
+	if opts.Fix {
+		for i := range checks {
+			c := &checks[i]
+			if c.Status == CheckOK || c.fix == nil {
+				continue
+			}
+			if err := c.fix(); err != nil {
+				c.Message = fmt.Sprintf("%s (fix failed: %v)", c.Message, err)
+				continue
+			}
+			c.Fixed = true
+		}
+	}
+
+	return checks
+}
+
+func checkInstallDir(dir string) Check {
+	c := Check{Name: "install dir writable", Status: CheckOK, Message: dir}
+
+	if !CheckDirExist(dir) {
+		c.Status = CheckFail
+		c.Message = "install dir does not exist: " + dir
+		c.Suggestion = "mkdir -p " + dir
+		c.fix = func() error { return os.MkdirAll(dir, 0755) }
+		return c
+	}
+
+	f, err := ioutil.TempFile(dir, ".doctor")
+	if err != nil {
+		c.Status = CheckFail
+		c.Message = fmt.Sprintf("install dir is not writable: %v", err)
+		c.Suggestion = "check the owner and permissions of " + dir
+		return c
+	}
+	f.Close()
+	os.Remove(f.Name())
+	return c
+}
+
+func checkBinDir(binPath string) Check {
+	pathDir := Path(binPath)
+	c := Check{Name: "bin dir exists", Status: CheckOK, Message: pathDir}
+
+	if !CheckDirExist(pathDir) {
+		c.Status = CheckFail
+		c.Message = "bin dir does not exist: " + pathDir
+		c.Suggestion = "mkdir -p " + pathDir + " or pass another path with --bin"
+		c.fix = func() error { return os.MkdirAll(pathDir, 0755) }
+	}
+	return c
+}
+
+func checkBinDirInPath(binPath string) Check {
+	pathDir := filepath.Clean(Path(binPath))
+	c := Check{Name: "bin dir in PATH", Status: CheckOK, Message: pathDir}
+
+	for _, p := range NewCommand(activeTool.Name).PathList() {
+		if p != "" && filepath.Clean(p) == pathDir {
+			return c
+		}
+	}
+	c.Status = CheckFail
+	c.Message = pathDir + " is not in PATH"
+	c.Suggestion = "export PATH=" + pathDir + ":$PATH"
+	return c
+}
+
+func checkHelmInPath(binPath string, installDir string) Check {
+	c := Check{Name: activeTool.Name + " resolves to managed symlink", Status: CheckOK}
+
+	/* the first one found in PATH is the one the shell runs */
+	first := ""
+	next := NewCommand(activeTool.Name).Find()
+	for path := next(); len(path) > 0; path = next() {
+		if first == "" {
+			first = path
+		}
+	}
+
+	switch {
+	case first == "":
+		c.Status = CheckWarn
+		c.Message = activeTool.Name + " not found in PATH"
+		c.Suggestion = "run helmswitch to install a version"
+	case filepath.Clean(first) != filepath.Clean(binPath):
+		c.Status = CheckFail
+		c.Message = fmt.Sprintf("%s in PATH resolves to %s, not to %s", activeTool.Name, first, binPath)
+		c.Suggestion = fmt.Sprintf("remove %s or move %s earlier in PATH", first, Path(binPath))
+	case !isManagedLink(binPath, installDir):
+		c.Status = CheckWarn
+		c.Message = binPath + " is not a symlink managed by helmswitch"
+		c.Suggestion = "run helmswitch to replace it with a managed version"
+	default:
+		target, _ := os.Readlink(binPath)
+		c.Message = binPath + " -> " + target
+	}
+	return c
+}
+
+func checkDanglingSymlinks(binPath string, installDir string) Check {
+	pathDir := Path(binPath)
+	c := Check{Name: "dangling symlinks", Status: CheckOK, Message: "none in " + pathDir}
+
+	files, err := ioutil.ReadDir(pathDir)
+	if err != nil {
+		c.Status = CheckWarn
+		c.Message = fmt.Sprintf("unable to read %s: %v", pathDir, err)
+		return c
+	}
+
+	dangling := []string{}
+	for _, f := range files {
+		link := filepath.Join(pathDir, f.Name())
+		if f.Mode()&os.ModeSymlink == 0 || !isManagedLink(link, installDir) {
+			continue
+		}
+		if _, err := os.Stat(link); err != nil {
+			dangling = append(dangling, link)
+		}
+	}
+
+	if len(dangling) > 0 {
+		c.Status = CheckFail
+		c.Message = "symlinks to missing versions: " + strings.Join(dangling, ", ")
+		c.Suggestion = "remove them and switch to an installed version"
+		c.fix = func() error {
+			for _, link := range dangling {
+				Log.Debugf("remove symlink %s", link)
+				if err := os.Remove(link); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+	return c
+}
+
+// orphanRegex : downloads left by an interrupted install, archives and checksums
+var orphanRegex = regexp.MustCompile(`\.(tar\.gz|tgz|tar\.xz|tar\.bz2|zip|sha256)$|^checksums\.txt$`)
+
+// extractDirPrefix : dirs Install extracts archives into, removed once the binary is out
+const extractDirPrefix = ".extract-"
+
+func checkOrphans(installDir string) Check {
+	c := Check{Name: "orphaned archives", Status: CheckOK, Message: "none in " + installDir}
+
+	files, err := ioutil.ReadDir(installDir)
+	if err != nil {
+		c.Status = CheckWarn
+		c.Message = fmt.Sprintf("unable to read %s: %v", installDir, err)
+		return c
+	}
+
+	orphans := []string{}
+	for _, f := range files {
+		/* the other dirs are the stores of the other tools and the isolated homes */
+		if f.IsDir() && !strings.HasPrefix(f.Name(), extractDirPrefix) {
+			continue
+		}
+		if f.IsDir() || orphanRegex.MatchString(f.Name()) {
+			orphans = append(orphans, filepath.Join(installDir, f.Name()))
+		}
+	}
+
+	if len(orphans) > 0 {
+		c.Status = CheckWarn
+		c.Message = "leftover downloads: " + strings.Join(orphans, ", ")
+		c.Suggestion = "remove them, they are not used once a version is installed"
+		c.fix = func() error {
+			for _, orphan := range orphans {
+				Log.Debugf("remove %s", orphan)
+				if err := os.RemoveAll(orphan); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+	return c
+}
+
+func checkHistory(installDir string) Check {
+	path := filepath.Join(installDir, historyFile)
+	c := Check{Name: "history", Status: CheckOK, Message: path}
+
+	entries, invalid, err := readHistory(installDir)
+	if err != nil {
+		c.Status = CheckFail
+		c.Message = fmt.Sprintf("unable to read %s: %v", path, err)
+		return c
+	}
+
+	if len(invalid) > 0 {
+		quoted := []string{}
+		for _, line := range invalid {
+			quoted = append(quoted, fmt.Sprintf("%q", line))
+		}
+		c.Status = CheckFail
+		c.Message = "corrupt entries: " + strings.Join(quoted, ", ")
+		c.Suggestion = "remove the invalid lines from " + path
+		c.fix = func() error { return writeHistory(installDir, entries) }
+	}
+	return c
+}
+
+func checkState(installDir string) Check {
+	c := Check{Name: "state", Status: CheckOK, Message: filepath.Join(installDir, stateFile)}
+
+	missing, untracked, err := StateDrift(installDir)
+	if err != nil {
+		c.Status = CheckFail
+		c.Message = err.Error()
+		c.Suggestion = "move " + filepath.Join(installDir, stateFile) + " away, it is rebuilt from the installed binaries"
+		return c
+	}
+
+	problems := []string{}
+	if len(missing) > 0 {
+		problems = append(problems, "recorded but missing: "+strings.Join(missing, ", "))
+	}
+	if len(untracked) > 0 {
+		problems = append(problems, "installed but not recorded: "+strings.Join(untracked, ", "))
+	}
+
+	if len(problems) > 0 {
+		c.Status = CheckWarn
+		c.Message = strings.Join(problems, "; ")
+		c.Suggestion = "update the state from the binaries in " + installDir
+		c.fix = func() error { return ReconcileState(installDir) }
+	}
+	return c
+}
+
+// rateLimit : body of the github rate limit endpoint
+type rateLimit struct {
+	Resources struct {
+		Core struct {
+			Limit     int   `json:"limit"`
+			Remaining int   `json:"remaining"`
+			Reset     int64 `json:"reset"`
+		} `json:"core"`
+	} `json:"resources"`
+}
+
+func checkGitHub(rateLimitURL string) Check {
+	c := Check{Name: "github reachable", Status: CheckOK}
+
+	res, err := NewHTTPClient(time.Second * 10).Get(rateLimitURL)
+	if err != nil {
+		c.Status = CheckFail
+		c.Message = fmt.Sprintf("unable to reach GitHub: %v", err)
+		c.Suggestion = "check your network connection and proxy settings"
+		return c
+	}
+	defer res.Body.Close()
+	LogResponse(res)
+
+	var limit rateLimit
+	if err := json.NewDecoder(res.Body).Decode(&limit); err != nil || res.StatusCode != http.StatusOK {
+		c.Status = CheckFail
+		c.Message = fmt.Sprintf("unexpected answer from GitHub: %s", res.Status)
+		return c
+	}
+
+	core := limit.Resources.Core
+	reset := time.Unix(core.Reset, 0)
+	c.Message = fmt.Sprintf("%d of %d API requests remaining", core.Remaining, core.Limit)
+
+	switch {
+	case core.Remaining == 0:
+		c.Status = CheckFail
+		c.Message = fmt.Sprintf("API rate limit exhausted until %v", reset)
+		c.Suggestion = "wait for the rate limit to reset or switch to an installed version"
+	case core.Remaining < 10:
+		c.Status = CheckWarn
+		c.Message = fmt.Sprintf("%s, resets at %v", c.Message, reset)
+		c.Suggestion = "listing versions needs several requests, wait for the rate limit to reset"
+	}
+	return c
+}
+
+func checkBinaries(installDir string) []Check {
+	checks := []Check{}
+
+	for _, version := range ListInstalledVersions(installDir) {
+		/* each fix removes its own version */
+		version := version
+		binary := filepath.Join(installDir, activeTool.Prefix()+version)
+		c := Check{Name: "checksum " + activeTool.Prefix() + version, Status: CheckOK}
+
+		sum, err := VerifyInstalled(installDir, version)
+		switch {
+		case err == nil:
+			c.Message = sum
+		case err == ErrNoChecksum:
+			c.Status = CheckWarn
+			c.Message = err.Error()
+			c.Suggestion = "remove " + binary + " and run helmswitch " + version + " to reinstall it"
+		case sum == "":
+			c.Status = CheckFail
+			c.Message = fmt.Sprintf("unable to read binary: %v", err)
+		default:
+			c.Status = CheckFail
+			c.Message = err.Error()
+			c.Suggestion = "remove " + binary + " and run helmswitch " + version + " to reinstall it"
+			c.fix = func() error { return RemoveInstalled(installDir, version) }
+		}
+		checks = append(checks, c)
+	}
+
+	return checks
+}
+
+// isManagedLink : check the symlink points into the helmswitch store
+func isManagedLink(link string, installDir string) bool {
+	if !CheckSymlink(link) {
+		return false
+	}
+	target, err := os.Readlink(link)
+	if err != nil {
+		return false
+	}
+	return strings.HasPrefix(filepath.Clean(target), filepath.Clean(installDir)+string(os.PathSeparator))
+}
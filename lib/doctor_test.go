@@ -0,0 +1,118 @@
+package lib_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tokiwong/helm-switcher/lib"
+)
+
+// TestDoctor : create a broken store, run doctor with fix, check problems are reported and fixed
+func TestDoctor(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"resources":{"core":{"limit":60,"remaining":0,"reset":1600000000}}}`)
+	}))
+	defer server.Close()
+
+	root, err := ioutil.TempDir("", "helmswitch-doctor")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	installDir := filepath.Join(root, "store") + "/"
+	binDir := filepath.Join(root, "bin")
+	createDirIfNotExist(installDir)
+	createDirIfNotExist(binDir)
+
+	/* orphaned archive, corrupt history, tampered binary, dangling symlink */
+	createFile(installDir + "helm-v3.3.0-linux-amd64.tar.gz")
+	createDirIfNotExist(installDir + ".extract-123456/linux-amd64")
+
+	/* the store of a tool named with a dash and the isolated homes are not leftovers */
+	createDirIfNotExist(installDir + "chart-testing")
+	ioutil.WriteFile(installDir+"chart-testing/chart-testing_3.0.0", []byte("ct"), 0755)
+	createDirIfNotExist(installDir + "homes/v3")
+	lib.AddHistory(installDir, lib.NewHistoryEntry("3.3.0", "", lib.TriggerArgument), 10)
+	history, _ := os.OpenFile(installDir+"history.json", os.O_APPEND|os.O_WRONLY, 0644)
+	history.WriteString("not-a-version\n")
+	history.Close()
+	ioutil.WriteFile(installDir+"helm_3.3.0", []byte("original"), 0755)
+	lib.RecordInstall(installDir, "3.3.0", "", "", "")
+	ioutil.WriteFile(installDir+"helm_3.3.0", []byte("tampered"), 0755)
+
+	/* untouched versions, before and after the tampered one */
+	for _, version := range []string{"2.16.0", "3.4.0"} {
+		ioutil.WriteFile(installDir+"helm_"+version, []byte("helm "+version), 0755)
+		lib.RecordInstall(installDir, version, "", "", "")
+	}
+	os.Symlink(installDir+"helm_9.9.9", filepath.Join(binDir, "helm"))
+
+	checks := lib.Doctor(lib.DoctorOptions{
+		InstallDir:   installDir,
+		BinPath:      filepath.Join(binDir, "helm"),
+		RateLimitURL: server.URL,
+		Fix:          true,
+	})
+
+	expected := map[string]string{
+		"orphaned archives":   lib.CheckWarn,
+		"history":             lib.CheckFail,
+		"checksum helm_3.3.0": lib.CheckFail,
+		"dangling symlinks":   lib.CheckFail,
+		"github reachable":    lib.CheckFail,
+	}
+
+	for _, c := range checks {
+		status, ok := expected[c.Name]
+		if !ok {
+			continue
+		}
+		if c.Status == status {
+			t.Logf("Check %q is %v [expected]", c.Name, c.Status)
+		} else {
+			t.Errorf("Check %q is %v, expected %v: %v [unexpected]", c.Name, c.Status, status, c.Message)
+		}
+	}
+
+	if checkFileExist(installDir + "helm-v3.3.0-linux-amd64.tar.gz") {
+		t.Error("Orphaned archive was not removed [unexpected]")
+	}
+
+	if checkFileExist(installDir + ".extract-123456") {
+		t.Error("Interrupted extraction dir was not removed [unexpected]")
+	}
+
+	if checkFileExist(installDir+"chart-testing/chart-testing_3.0.0") && checkFileExist(installDir+"homes/v3") {
+		t.Log("Tool store and homes kept [expected]")
+	} else {
+		t.Error("Tool store or homes removed as leftovers [unexpected]")
+	}
+
+	if checkFileExist(installDir + "helm_3.3.0") {
+		t.Error("Tampered binary was not removed [unexpected]")
+	}
+
+	if checkFileExist(installDir+"helm_2.16.0") && checkFileExist(installDir+"helm_3.4.0") {
+		t.Log("Untouched binaries kept [expected]")
+	} else {
+		t.Error("Untouched binary removed [unexpected]")
+	}
+
+	if _, err := os.Lstat(filepath.Join(binDir, "helm")); err == nil {
+		t.Error("Dangling symlink was not removed [unexpected]")
+	}
+
+	lines, _ := lib.ReadLines(installDir + "history.json")
+	if len(lines) == 1 {
+		t.Log("Corrupt history entries removed [expected]")
+	} else {
+		t.Errorf("History not repaired: %v [unexpected]", lines)
+	}
+}
@@ -1,11 +1,14 @@
 package lib
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
 	"strings"
+	"time"
 )
 
 // DownloadFromURL : Downloads the binary from the source url
@@ -13,29 +16,75 @@ func DownloadFromURL(installLocation string, url string) (string, error) {
 
 	tokens := strings.Split(url, "/")
 	fileName := tokens[len(tokens)-1]
-	fmt.Println("Downloading", url, "to", fileName)
-	fmt.Println("Downloading ...")
+	Log.Infof("Downloading %s to %s", url, fileName)
+	Log.Infof("Downloading ...")
 
+	Log.Debugf("create %s", installLocation+fileName)
 	output, err := os.Create(installLocation + fileName)
 	if err != nil {
-		fmt.Println("Error while creating", installLocation+fileName, "-", err)
+		Log.Errorf("Error while creating %s - %v", installLocation+fileName, err)
 		return "", err
 	}
 	defer output.Close()
 
-	response, err := http.Get(url)
+	response, err := NewHTTPClient(0).Get(url)
 	if err != nil {
-		fmt.Println("Error while downloading", url, "-", err)
+		Log.Errorf("Error while downloading %s - %v", url, err)
 		return "", err
 	}
 	defer response.Body.Close()
+	LogResponse(response)
+
+	if response.StatusCode != http.StatusOK {
+		Log.Errorf("Error while downloading %s - %s", RedactURL(url), response.Status)
+		return "", fmt.Errorf("%s: %s", RedactURL(url), response.Status)
+	}
 
 	n, errCopy := io.Copy(output, response.Body)
 	if errCopy != nil {
-		fmt.Println("Error while downloading", url, "-", errCopy)
+		Log.Errorf("Error while downloading %s - %v", url, errCopy)
 		return "", errCopy
 	}
 
-	fmt.Println(n, "bytes downloaded.")
+	Log.Verbosef("%d bytes downloaded.", n)
 	return installLocation + fileName, nil
 }
+
+// NewHTTPClient : http client that logs redirects at debug level, timeout of 0 means no timeout
+func NewHTTPClient(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout: timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			Log.Debugf("redirect %s -> %s", RedactURL(via[len(via)-1].URL.String()), RedactURL(req.URL.String()))
+			if len(via) >= 10 {
+				return errors.New("stopped after 10 redirects")
+			}
+			return nil
+		},
+	}
+}
+
+// LogResponse : print request, status and rate limit headers at debug level
+func LogResponse(res *http.Response) {
+	Log.Debugf("%s %s -> %s", res.Request.Method, RedactURL(res.Request.URL.String()), res.Status)
+	for _, header := range []string{"X-Ratelimit-Limit", "X-Ratelimit-Remaining", "X-Ratelimit-Reset", "Content-Length"} {
+		if v := res.Header.Get(header); v != "" {
+			Log.Debugf("  %s: %s", header, v)
+		}
+	}
+}
+
+// RedactURL : hide the api client secret from urls before logging them
+func RedactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	q := u.Query()
+	if q.Get("clientSecret") == "" {
+		return rawURL
+	}
+	q.Set("clientSecret", "REDACTED")
+	u.RawQuery = q.Encode()
+	return u.String()
+}
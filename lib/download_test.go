@@ -2,49 +2,52 @@ package lib_test
 
 import (
 	"fmt"
-	"log"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"os"
-	"os/user"
 	"runtime"
 	"testing"
 
 	lib "github.com/tokiwong/helm-switcher/lib"
 )
 
+/* a release page serving helm_<os>_<arch> for the versions it knows, 404 for the others */
+func newReleaseServer(versions ...string) *httptest.Server {
+	mux := http.NewServeMux()
+	for _, version := range versions {
+		body := "helm " + version
+		mux.HandleFunc("/v"+version+"/helm_"+runtime.GOOS+"_"+runtime.GOARCH, func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, body)
+		})
+	}
+	return httptest.NewServer(mux)
+}
+
 // TestDownloadFromURL_FileNameMatch : Check expected filename exist when downloaded
 func TestDownloadFromURL_FileNameMatch(t *testing.T) {
 
-	helmURL := "https://github.com/helm/helm/releases/download/"
+	server := newReleaseServer("0.13.9", "0.14.11")
+	defer server.Close()
+
+	helmURL := server.URL + "/"
 	installVersion := "helm_"
-	installPath := "/.helm.versions_test/"
 	goarch := runtime.GOARCH
 	goos := runtime.GOOS
 
-	// get current user
-	usr, errCurr := user.Current()
-	if errCurr != nil {
-		log.Fatal(errCurr)
-	}
-
-	fmt.Printf("Current user: %v \n", usr.HomeDir)
-	installLocation := usr.HomeDir + installPath
-
-	// create /.helm.versions_test/ directory to store code
-	if _, err := os.Stat(installLocation); os.IsNotExist(err) {
-		log.Printf("Creating directory for helm: %v", installLocation)
-		err = os.MkdirAll(installLocation, 0755)
-		if err != nil {
-			fmt.Printf("Unable to create directory for helm: %v", installLocation)
-			panic(err)
-		}
+	installLocation, err := ioutil.TempDir("", "helmswitch-download")
+	if err != nil {
+		t.Fatal(err)
 	}
+	installLocation += "/"
+	defer cleanUp(installLocation)
 
 	/* test download lowest helm version */
 	lowestVersion := "0.13.9"
 
 	url := helmURL + "v" + lowestVersion + "/" + installVersion + goos + "_" + goarch
-	expectedFile := usr.HomeDir + installPath + installVersion + goos + "_" + goarch
+	expectedFile := installLocation + installVersion + goos + "_" + goarch
 	installedFile, _ := lib.DownloadFromURL(installLocation, url)
 
 	if installedFile == expectedFile {
@@ -61,7 +64,7 @@ func TestDownloadFromURL_FileNameMatch(t *testing.T) {
 	latestVersion := "0.14.11"
 
 	url = helmURL + "v" + latestVersion + "/" + installVersion + goos + "_" + goarch
-	expectedFile = usr.HomeDir + installPath + installVersion + goos + "_" + goarch
+	expectedFile = installLocation + installVersion + goos + "_" + goarch
 	installedFile, _ = lib.DownloadFromURL(installLocation, url)
 
 	if installedFile == expectedFile {
@@ -73,73 +76,42 @@ func TestDownloadFromURL_FileNameMatch(t *testing.T) {
 		t.Logf("Downloaded file name %v", installedFile)
 		t.Error("Downoad file name mismatches expected file")
 	}
-
-	cleanUp(installLocation)
 }
 
 // TestDownloadFromURL_FileExist : Check expected file exist when downloaded
 func TestDownloadFromURL_FileExist(t *testing.T) {
 
-	helmURL := "https://github.com/helm/helm/releases/download/"
+	server := newReleaseServer("0.13.9", "0.14.11")
+	defer server.Close()
+
+	helmURL := server.URL + "/"
 	installVersion := "helm_"
-	installPath := "/.helm.versions_test/"
 	goarch := runtime.GOARCH
 	goos := runtime.GOOS
 
-	// get current user
-	usr, errCurr := user.Current()
-	if errCurr != nil {
-		log.Fatal(errCurr)
+	installLocation, err := ioutil.TempDir("", "helmswitch-download")
+	if err != nil {
+		t.Fatal(err)
 	}
-
-	fmt.Printf("Current user: %v \n", usr.HomeDir)
-	installLocation := usr.HomeDir + installPath
-
-	// create /.helm.versions_test/ directory to store code
-	if _, err := os.Stat(installLocation); os.IsNotExist(err) {
-		log.Printf("Creating directory for helm: %v", installLocation)
-		err = os.MkdirAll(installLocation, 0755)
-		if err != nil {
-			fmt.Printf("Unable to create directory for helm: %v", installLocation)
-			panic(err)
+	installLocation += "/"
+	defer cleanUp(installLocation)
+
+	for _, version := range []string{"0.13.9", "0.14.11"} {
+		url := helmURL + "v" + version + "/" + installVersion + goos + "_" + goarch
+		expectedFile := installLocation + installVersion + goos + "_" + goarch
+		installedFile, _ := lib.DownloadFromURL(installLocation, url)
+
+		content, _ := ioutil.ReadFile(expectedFile)
+		if checkFileExist(expectedFile) && string(content) == "helm "+version {
+			t.Logf("Expected file %v", expectedFile)
+			t.Logf("Downloaded file %v", installedFile)
+			t.Log("Download file matches expected file")
+		} else {
+			t.Logf("Expected file %v", expectedFile)
+			t.Logf("Downloaded file %v", installedFile)
+			t.Error("Downoad file mismatches expected file")
 		}
 	}
-
-	/* test download lowest helm version */
-	lowestVersion := "0.13.9"
-
-	url := helmURL + "v" + lowestVersion + "/" + installVersion + goos + "_" + goarch
-	expectedFile := usr.HomeDir + installPath + installVersion + goos + "_" + goarch
-	installedFile, _ := lib.DownloadFromURL(installLocation, url)
-
-	if checkFileExist(expectedFile) {
-		t.Logf("Expected file %v", expectedFile)
-		t.Logf("Downloaded file %v", installedFile)
-		t.Log("Download file matches expected file")
-	} else {
-		t.Logf("Expected file %v", expectedFile)
-		t.Logf("Downloaded file %v", installedFile)
-		t.Error("Downoad file mismatches expected file")
-	}
-
-	/* test download latest helm version */
-	latestVersion := "0.14.11"
-
-	url = helmURL + "v" + latestVersion + "/" + installVersion + goos + "_" + goarch
-	expectedFile = usr.HomeDir + installPath + installVersion + goos + "_" + goarch
-	installedFile, _ = lib.DownloadFromURL(installLocation, url)
-
-	if checkFileExist(expectedFile) {
-		t.Logf("Expected file %v", expectedFile)
-		t.Logf("Downloaded file %v", installedFile)
-		t.Log("Download file matches expected file")
-	} else {
-		t.Logf("Expected file %v", expectedFile)
-		t.Logf("Downloaded file %v", installedFile)
-		t.Error("Downoad file mismatches expected file")
-	}
-
-	cleanUp(installLocation)
 }
 
 func TestDownloadFromURL_Valid(t *testing.T) {
@@ -154,3 +126,23 @@ func TestDownloadFromURL_Valid(t *testing.T) {
 		t.Logf("Valid URL from %v", url)
 	}
 }
+
+// TestDownloadFromURL_NotFound : a download answered with an error status should fail
+func TestDownloadFromURL_NotFound(t *testing.T) {
+
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	installLocation, err := ioutil.TempDir("", "helmswitch-download")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(installLocation)
+
+	installedFile, err := lib.DownloadFromURL(installLocation+"/", server.URL+"/helm-v9.9.9-linux-amd64.tar.gz")
+	if err != nil && installedFile == "" {
+		t.Logf("Download failed: %v [expected]", err)
+	} else {
+		t.Errorf("Download of a missing file succeeded: %v [unexpected]", installedFile)
+	}
+}
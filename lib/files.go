@@ -1,15 +1,12 @@
 package lib
 
 import (
-	"archive/tar"
 	"bufio"
 	"bytes"
-	"compress/gzip"
 	"crypto/sha256"
 	"fmt"
 	"io"
 	"io/ioutil"
-	"log"
 	"os"
 	"path/filepath"
 	"strings"
@@ -17,9 +14,10 @@ import (
 
 // RenameFile : rename file name
 func RenameFile(src string, dest string) {
+	Log.Debugf("rename %s -> %s", src, dest)
 	err := os.Rename(src, dest)
 	if err != nil {
-		fmt.Println(err)
+		Log.Errorf("%v", err)
 		return
 	}
 }
@@ -32,6 +30,7 @@ func RemoveFiles(src string) {
 		panic(err)
 	}
 	for _, f := range files {
+		Log.Debugf("remove %s", f)
 		if err := os.Remove(f); err != nil {
 			panic(err)
 		}
@@ -50,10 +49,10 @@ func CheckFileExist(file string) bool {
 //CreateDirIfNotExist : create directory if directory does not exist
 func CreateDirIfNotExist(dir string) {
 	if _, err := os.Stat(dir); os.IsNotExist(err) {
-		log.Printf("Creating directory for helm: %v", dir)
+		Log.Infof("Creating directory for %s: %v", activeTool.Name, dir)
 		err = os.MkdirAll(dir, 0755)
 		if err != nil {
-			fmt.Printf("Unable to create directory for helm: %v", dir)
+			Log.Errorf("Unable to create directory for %s: %v", activeTool.Name, dir)
 			panic(err)
 		}
 	}
@@ -73,7 +72,7 @@ func WriteLines(lines []string, path string) (err error) {
 	for _, item := range lines {
 		_, err := file.WriteString(strings.TrimSpace(item) + "\n")
 		if err != nil {
-			fmt.Println(err)
+			Log.Errorf("%v", err)
 			break
 		}
 	}
@@ -118,7 +117,7 @@ func IsDirEmpty(name string) bool {
 
 	f, err := os.Open(name)
 	if err != nil {
-		log.Fatal(err)
+		Fail("%v", err)
 	}
 	defer f.Close()
 
@@ -136,7 +135,7 @@ func CheckDirHasHelmBin(dir, prefix string) bool {
 
 	files, err := ioutil.ReadDir(dir)
 	if err != nil {
-		log.Fatal(err)
+		Fail("%v", err)
 		//return exist, err
 	}
 	res := []string{}
@@ -165,103 +164,47 @@ func Path(value string) string {
 	return filepath.Dir(value)
 }
 
-func Untar(dest string, r io.Reader) error {
+// VerifyChecksum : compare the sha256 of fileInstalled with the one in the checksum file chkInstalled, in the given format
+func VerifyChecksum(fileInstalled string, chkInstalled string, format string) error {
 
-	gzr, err := gzip.NewReader(r)
+	Log.Verbosef("Verifying SHA sum")
+
+	fileSha, err := FileChecksum(fileInstalled)
 	if err != nil {
 		return err
 	}
-	defer gzr.Close()
-
-	tr := tar.NewReader(gzr)
-
-	for {
-		header, err := tr.Next()
-
-		switch {
-
-		// if no more files are found return
-		case err == io.EOF:
-			return nil
-
-		// return any other error
-		case err != nil:
-			return err
-
-		// if the header is nil, just skip it (not sure how this happens)
-		case header == nil:
-			continue
-		}
+	Log.Verbosef("%s", fileSha)
 
-		// the target location where the dir/file should be created
-		target := filepath.Join(dest, header.Name)
-
-		// the following switch could also be done using fi.Mode(), not sure if there
-		// a benefit of using one vs. the other.
-		// fi := header.FileInfo()
-
-		// check the file type
-		switch header.Typeflag {
-
-		// if its a dir and it doesn't exist create it
-		case tar.TypeDir:
-			if _, err := os.Stat(target); err != nil {
-				if err := os.MkdirAll(target, 0755); err != nil {
-					return err
-				}
-			}
-
-		// if it's a file create it
-		case tar.TypeReg:
-			f, err := os.OpenFile(target, os.O_CREATE|os.O_RDWR, os.FileMode(header.Mode))
-			if err != nil {
-				return err
-			}
+	chkContent, err := ioutil.ReadFile(chkInstalled)
+	if err != nil {
+		return err
+	}
 
-			// copy over contents
-			if _, err := io.Copy(f, tr); err != nil {
-				return err
-			}
+	chkOut, err := ParseChecksum(string(chkContent), filepath.Base(fileInstalled), format)
+	if err != nil {
+		return err
+	}
+	Log.Verbosef("%s", chkOut)
 
-			// manually close here after each file operation; defering would cause each file close
-			// to wait until all operations have completed.
-			f.Close()
-		}
+	if fileSha != chkOut {
+		return fmt.Errorf("expecting %s, received %s for %s", chkOut, fileSha, filepath.Base(fileInstalled))
 	}
+	Report.Checksum = fileSha
+	Log.Infof("SHA sum verified")
+	return nil
 }
 
-func VerifyChecksum(fileInstalled string, chkInstalled string) bool {
-
-	fmt.Println("Verifying SHA sum")
-
-	file, err := os.Open(fileInstalled)
+// FileChecksum : sha256 of a file as a hex string
+func FileChecksum(path string) (string, error) {
+	file, err := os.Open(path)
 	if err != nil {
-		log.Fatal(err)
+		return "", err
 	}
 	defer file.Close()
 
 	fileHash := sha256.New()
 	if _, err := io.Copy(fileHash, file); err != nil {
-		log.Fatal(err)
+		return "", err
 	}
-
-	fileSha := fmt.Sprintf("%x", fileHash.Sum(nil))
-	fmt.Println(fileSha)
-
-	chkContent, err := ioutil.ReadFile(chkInstalled)
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	chkOut := string(chkContent)
-	fmt.Println(chkOut)
-
-	if fileSha != chkOut[0:64] {
-		log.Fatal("Expecting: " + chkOut + ", Received: " + fileSha + ". Aborting.")
-		return false
-	}
-	os.Remove(chkInstalled)
-	fmt.Println("SHA sum verified")
-	return true
-
+	return fmt.Sprintf("%x", fileHash.Sum(nil)), nil
 }
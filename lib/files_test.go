@@ -4,9 +4,11 @@ import (
 	"bufio"
 	"bytes"
 	"io"
+	"io/ioutil"
 	"log"
 	"os"
 	"os/user"
+	"path/filepath"
 	"regexp"
 	"runtime"
 	"strings"
@@ -319,3 +321,31 @@ func TestCheckDirHasHelmBin(t *testing.T) {
 
 	cleanUp(installLocation)
 }
+
+// TestVerifyChecksum : a matching checksum should verify, a mismatch should return an error instead of exiting
+func TestVerifyChecksum(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "helmswitch-checksum")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	archive := filepath.Join(dir, "helm-v3.3.0-linux-amd64.tar.gz")
+	ioutil.WriteFile(archive, []byte("archive"), 0644)
+	sum, _ := lib.FileChecksum(archive)
+	ioutil.WriteFile(archive+".sha256", []byte(sum+"\n"), 0644)
+
+	if err := lib.VerifyChecksum(archive, archive+".sha256", lib.ChecksumSHA256); err == nil {
+		t.Log("Checksum verified [expected]")
+	} else {
+		t.Errorf("Checksum not verified: %v [unexpected]", err)
+	}
+
+	ioutil.WriteFile(archive, []byte("tampered"), 0644)
+	if err := lib.VerifyChecksum(archive, archive+".sha256", lib.ChecksumSHA256); err != nil {
+		t.Logf("Mismatch reported: %v [expected]", err)
+	} else {
+		t.Error("Tampered archive verified [unexpected]")
+	}
+}
@@ -0,0 +1,212 @@
+package lib
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+)
+
+// historyFile : one JSON object per line, oldest first
+const historyFile = "history.json"
+
+// What caused a switch
+const (
+	TriggerMenu     = "menu"
+	TriggerArgument = "argument"
+	TriggerPrevious = "previous"
+	TriggerMigrated = "migrated"
+)
+
+// HistoryEntry : a switch to a helm version
+type HistoryEntry struct {
+	Version string    `json:"version"`
+	Time    time.Time `json:"time"`
+	Dir     string    `json:"dir,omitempty"`
+	BinPath string    `json:"bin_path,omitempty"`
+	Trigger string    `json:"trigger"`
+}
+
+// NewHistoryEntry : a switch happening now from the current directory
+func NewHistoryEntry(version string, binPath string, trigger string) HistoryEntry {
+	cwd, _ := os.Getwd()
+	return HistoryEntry{
+		Version: version,
+		Time:    time.Now().UTC(),
+		Dir:     cwd,
+		BinPath: binPath,
+		Trigger: trigger,
+	}
+}
+
+// LoadHistory : switches recorded in dir, oldest first; unreadable lines are skipped
+func LoadHistory(dir string) ([]HistoryEntry, error) {
+	entries, invalid, err := readHistory(dir)
+	if len(invalid) > 0 {
+		Log.Verbosef("Skipping %d invalid entries in %s", len(invalid), filepath.Join(dir, historyFile))
+	}
+	return entries, err
+}
+
+// readHistory : parse the history, returning the lines that are not valid entries separately
+func readHistory(dir string) ([]HistoryEntry, []string, error) {
+	path := filepath.Join(dir, historyFile)
+
+	content, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		entries, err := migrateRecentFile(dir)
+		return entries, nil, err
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	entries := []HistoryEntry{}
+	invalid := []string{}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var entry HistoryEntry
+		if err := json.Unmarshal(line, &entry); err != nil || !ValidVersionFormat(entry.Version) {
+			invalid = append(invalid, string(line))
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, invalid, scanner.Err()
+}
+
+// migrateRecentFile : turn the RECENT file of older releases into history entries
+func migrateRecentFile(dir string) ([]HistoryEntry, error) {
+	path := filepath.Join(dir, recentFile)
+	info, err := os.Stat(path)
+	if err != nil {
+		return []HistoryEntry{}, nil
+	}
+
+	lines, err := ReadLines(path)
+	if err != nil {
+		return nil, err
+	}
+
+	/* RECENT lists the most recent version first and has no timestamps */
+	semverRegex := regexp.MustCompile(`\A\d+(\.\d+){2}\z`)
+	entries := []HistoryEntry{}
+	for i := len(lines) - 1; i >= 0; i-- {
+		if !semverRegex.MatchString(lines[i]) {
+			Report.Warn("dropping invalid entry %q from %s", lines[i], path)
+			continue
+		}
+		entries = append(entries, HistoryEntry{
+			Version: lines[i],
+			Time:    info.ModTime().UTC().Add(-time.Duration(i) * time.Minute),
+			Trigger: TriggerMigrated,
+		})
+	}
+
+	if err := writeHistory(dir, entries); err != nil {
+		return nil, err
+	}
+	RemoveFiles(path)
+	return entries, nil
+}
+
+// writeHistory : replace the history with entries
+func writeHistory(dir string, entries []HistoryEntry) error {
+	var buffer bytes.Buffer
+	enc := json.NewEncoder(&buffer)
+	for _, entry := range entries {
+		if err := enc.Encode(entry); err != nil {
+			return err
+		}
+	}
+
+	path := filepath.Join(dir, historyFile)
+	Log.Debugf("write %s", path)
+	if err := ioutil.WriteFile(path+".tmp", buffer.Bytes(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// AddHistory : record a switch, keeping the last size entries
+func AddHistory(dir string, entry HistoryEntry, size int) error {
+	entries, err := LoadHistory(dir)
+	if err != nil {
+		return err
+	}
+
+	entries = append(entries, entry)
+	if size > 0 && len(entries) > size {
+		entries = entries[len(entries)-size:]
+	}
+	return writeHistory(dir, entries)
+}
+
+// PreviousVersion : the version used before current, the latest version in the history when current is unknown
+func PreviousVersion(entries []HistoryEntry, current string) (string, bool) {
+	if current == "" && len(entries) > 0 {
+		current = entries[len(entries)-1].Version
+	}
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].Version != current {
+			return entries[i].Version, true
+		}
+	}
+	return "", false
+}
+
+// RecentVersions : up to n versions ranked by how often and how lately they were used
+func RecentVersions(entries []HistoryEntry, n int, now time.Time) []string {
+	score := map[string]int{}
+	lastUsed := map[string]time.Time{}
+
+	for _, entry := range entries {
+		score[entry.Version] += recencyWeight(now.Sub(entry.Time))
+		if entry.Time.After(lastUsed[entry.Version]) {
+			lastUsed[entry.Version] = entry.Time
+		}
+	}
+
+	versions := []string{}
+	for version := range score {
+		versions = append(versions, version)
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		a, b := versions[i], versions[j]
+		if score[a] != score[b] {
+			return score[a] > score[b]
+		}
+		return lastUsed[a].After(lastUsed[b])
+	})
+
+	if len(versions) > n {
+		versions = versions[:n]
+	}
+	return versions
+}
+
+// recencyWeight : a use counts for less the older it is
+func recencyWeight(age time.Duration) int {
+	day := 24 * time.Hour
+	switch {
+	case age < day:
+		return 100
+	case age < 7*day:
+		return 70
+	case age < 30*day:
+		return 50
+	case age < 90*day:
+		return 30
+	default:
+		return 10
+	}
+}
@@ -0,0 +1,113 @@
+package lib_test
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/tokiwong/helm-switcher/lib"
+)
+
+// TestAddHistory : only the configured number of entries is kept
+func TestAddHistory(t *testing.T) {
+
+	installDir, err := ioutil.TempDir("", "helmswitch-history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(installDir)
+
+	for _, version := range []string{"2.16.1", "3.2.4", "3.3.0", "3.3.1"} {
+		if err := lib.AddHistory(installDir, lib.NewHistoryEntry(version, "/usr/local/bin/helm", lib.TriggerArgument), 3); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, err := lib.LoadHistory(installDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) == 3 && entries[0].Version == "3.2.4" && entries[2].Version == "3.3.1" {
+		t.Logf("Oldest entry dropped %v [expected]", entries)
+	} else {
+		t.Errorf("Unexpected history %v [unexpected]", entries)
+	}
+}
+
+// TestLoadHistory_RecentFile : the RECENT file of older releases becomes history
+func TestLoadHistory_RecentFile(t *testing.T) {
+
+	installDir, err := ioutil.TempDir("", "helmswitch-history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(installDir)
+
+	lib.WriteLines([]string{"3.3.0", "garbage", "2.16.1"}, installDir+"/RECENT")
+
+	entries, err := lib.LoadHistory(installDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) == 2 && entries[0].Version == "2.16.1" && entries[1].Version == "3.3.0" {
+		t.Log("Valid RECENT entries migrated, most recent last [expected]")
+	} else {
+		t.Errorf("Unexpected history %v [unexpected]", entries)
+	}
+
+	if checkFileExist(installDir + "/RECENT") {
+		t.Error("RECENT should be removed once migrated [unexpected]")
+	}
+}
+
+// TestRecentVersions : frequently used versions rank above a single recent use
+func TestRecentVersions(t *testing.T) {
+
+	now := time.Now()
+	entries := []lib.HistoryEntry{
+		{Version: "2.16.1", Time: now.Add(-200 * 24 * time.Hour)},
+		{Version: "3.2.4", Time: now.Add(-3 * 24 * time.Hour)},
+		{Version: "3.2.4", Time: now.Add(-2 * 24 * time.Hour)},
+		{Version: "3.3.0", Time: now.Add(-1 * time.Hour)},
+	}
+
+	recent := lib.RecentVersions(entries, 2, now)
+
+	if len(recent) == 2 && recent[0] == "3.2.4" && recent[1] == "3.3.0" {
+		t.Logf("Recent versions %v [expected]", recent)
+	} else {
+		t.Errorf("Unexpected recent versions %v [unexpected]", recent)
+	}
+}
+
+// TestPreviousVersion : the version used before the active one, skipping repeated switches
+func TestPreviousVersion(t *testing.T) {
+
+	entries := []lib.HistoryEntry{
+		{Version: "2.16.1"},
+		{Version: "3.2.4"},
+		{Version: "3.3.0"},
+		{Version: "3.3.0"},
+	}
+
+	if previous, ok := lib.PreviousVersion(entries, "3.3.0"); ok && previous == "3.2.4" {
+		t.Logf("Previous version %v [expected]", previous)
+	} else {
+		t.Errorf("Unexpected previous version %v [unexpected]", previous)
+	}
+
+	if previous, ok := lib.PreviousVersion(entries, ""); ok && previous == "3.2.4" {
+		t.Logf("Previous version without active version %v [expected]", previous)
+	} else {
+		t.Errorf("Unexpected previous version %v [unexpected]", previous)
+	}
+
+	if _, ok := lib.PreviousVersion(entries[:1], "2.16.1"); !ok {
+		t.Log("No previous version with a single entry [expected]")
+	} else {
+		t.Error("Expected no previous version [unexpected]")
+	}
+}
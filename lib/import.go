@@ -0,0 +1,266 @@
+package lib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/tokiwong/helm-switcher/modal"
+)
+
+// What helmswitch import did with a binary
+const (
+	ImportAdopted = "adopted"
+	/* the version is already in the store */
+	ImportSkipped = "skipped"
+	ImportFailed  = "failed"
+)
+
+// ErrChecksumMismatch : the binary differs from the one of the published release
+var ErrChecksumMismatch = errors.New("binary differs from the published release")
+
+/* the version printed by helm version --client --short, eg. Client: v2.16.9+g8ad7037 or v3.3.0+g8a4aeec */
+var clientVersionRegex = regexp.MustCompile(`v?(\d+\.\d+\.\d+(?:-[0-9A-Za-z.]+)?)`)
+
+// ImportSource : where another version manager or package manager keeps its binaries
+type ImportSource struct {
+	Name     string
+	Patterns []string
+	/* built from source, so never the binary of the published release */
+	Unverifiable bool
+}
+
+// ImportCandidate : a binary found outside the store, and what became of it
+type ImportCandidate struct {
+	Path     string `json:"path"`
+	Source   string `json:"source"`
+	Version  string `json:"version,omitempty"`
+	Status   string `json:"status,omitempty"`
+	Verified bool   `json:"verified"`
+	Reason   string `json:"reason,omitempty"`
+
+	unverifiable bool
+}
+
+// ImportSources : the places the binaries of the active tool are looked for
+func ImportSources(home string) []ImportSource {
+	name := activeTool.Name
+	asdf := os.Getenv("ASDF_DATA_DIR")
+	if asdf == "" {
+		asdf = filepath.Join(home, ".asdf")
+	}
+	helmenv := os.Getenv("HELMENV_ROOT")
+	if helmenv == "" {
+		helmenv = filepath.Join(home, ".helmenv")
+	}
+
+	cellars := []string{}
+	for _, prefix := range []string{"/usr/local", "/opt/homebrew", "/home/linuxbrew/.linuxbrew", filepath.Join(home, ".linuxbrew")} {
+		cellars = append(cellars,
+			filepath.Join(prefix, "Cellar", name, "*", "bin", name),
+			filepath.Join(prefix, "Cellar", name+"@*", "*", "bin", name))
+	}
+
+	return []ImportSource{
+		{Name: "asdf", Patterns: []string{filepath.Join(asdf, "installs", name, "*", "bin", name)}},
+		{Name: name + "env", Patterns: []string{
+			filepath.Join(helmenv, "versions", "*", name),
+			filepath.Join(helmenv, "versions", "*", "bin", name),
+		}},
+		{Name: "homebrew", Patterns: cellars, Unverifiable: true},
+		{Name: "manual", Patterns: []string{filepath.Join("/usr/local/bin", name+"-v*")}},
+	}
+}
+
+// DiscoverBinaries : the executables matching the sources, once each, leaving out those of the store
+func DiscoverBinaries(sources []ImportSource, storeDir string) []ImportCandidate {
+	candidates := []ImportCandidate{}
+	seen := map[string]bool{}
+	store, _ := filepath.EvalSymlinks(storeDir)
+
+	for _, source := range sources {
+		for _, pattern := range source.Patterns {
+			paths, _ := filepath.Glob(pattern)
+			sort.Strings(paths)
+			for _, path := range paths {
+				real, err := filepath.EvalSymlinks(path)
+				if err != nil || seen[real] || !isExecutable(real) {
+					continue
+				}
+				if store != "" && strings.HasPrefix(real, store+string(os.PathSeparator)) {
+					continue
+				}
+				seen[real] = true
+				Log.Debugf("found %s (%s)", path, source.Name)
+				candidates = append(candidates, ImportCandidate{Path: path, Source: source.Name, unverifiable: source.Unverifiable})
+			}
+		}
+	}
+	return candidates
+}
+
+// BinaryVersion : the version a helm binary reports with version --client --short
+func BinaryVersion(path string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	Log.Debugf("exec %s version --client --short", path)
+	output, err := exec.CommandContext(ctx, path, "version", "--client", "--short").Output()
+	if err != nil {
+		return "", fmt.Errorf("%s version: %v", path, err)
+	}
+	match := clientVersionRegex.FindStringSubmatch(string(output))
+	if match == nil || !ValidVersionFormat(match[1]) {
+		return "", fmt.Errorf("%s version: unexpected output %q", path, strings.TrimSpace(string(output)))
+	}
+	return match[1], nil
+}
+
+// VerifyBinary : download the published archive of release, check it against its checksum and compare
+// its binary with the one at path; returns the archive url and sha256
+func VerifyBinary(path string, version string, release modal.Repo, goos string, goarch string) (string, string, error) {
+	archiveURL, err := activeTool.ArtifactURL(release, goos, goarch)
+	if err != nil {
+		return "", "", err
+	}
+	checksumURL, err := activeTool.ChecksumURL(release, goos, goarch)
+	if err != nil {
+		return archiveURL, "", err
+	}
+	if checksumURL == "" {
+		return archiveURL, "", fmt.Errorf("%s publishes no checksum", activeTool.Name)
+	}
+
+	tmp, err := ioutil.TempDir("", "helmswitch-import-")
+	if err != nil {
+		return archiveURL, "", err
+	}
+	defer os.RemoveAll(tmp)
+
+	archive := filepath.Join(tmp, filepath.Base(archiveURL))
+	var checksum strings.Builder
+	if err := downloadFile(archiveURL, archive); err != nil {
+		return archiveURL, "", err
+	}
+	if err := httpGet(checksumURL, &checksum); err != nil {
+		return archiveURL, "", err
+	}
+	expected, err := ParseChecksum(checksum.String(), filepath.Base(archive), activeTool.ChecksumFormat)
+	if err != nil {
+		return archiveURL, "", err
+	}
+	archiveSum, err := FileChecksum(archive)
+	if err != nil {
+		return archiveURL, "", err
+	}
+	if archiveSum != expected {
+		return archiveURL, "", fmt.Errorf("checksum mismatch for %s: expected %s, downloaded %s", archiveURL, expected, archiveSum)
+	}
+
+	binPath, err := activeTool.BinaryPath(version, goos, goarch)
+	if err != nil {
+		return archiveURL, archiveSum, err
+	}
+	extractDir := filepath.Join(tmp, "extract")
+	os.MkdirAll(extractDir, 0755)
+	layout, err := ExtractArchive(archive, extractDir, activeTool.Layout, []string{binPath})
+	if err != nil {
+		return archiveURL, archiveSum, err
+	}
+	published := filepath.Join(extractDir, binPath)
+	if layout == LayoutBinary {
+		published = archive
+	}
+
+	publishedSum, err := FileChecksum(published)
+	if err != nil {
+		return archiveURL, archiveSum, err
+	}
+	sum, err := FileChecksum(path)
+	if err != nil {
+		return archiveURL, archiveSum, err
+	}
+	Log.Verbosef("%s sha256 %s, published %s", path, sum, publishedSum)
+	if sum != publishedSum {
+		return archiveURL, archiveSum, ErrChecksumMismatch
+	}
+	return archiveURL, archiveSum, nil
+}
+
+// AdoptBinary : copy the binary at path into dir as helm_X.Y.Z, with the companions found next to it,
+// and record it in the state
+func AdoptBinary(dir string, path string, version string, sourceURL string, archiveSHA256 string) error {
+	binary := filepath.Join(dir, activeTool.Prefix()+version)
+	if err := copyExecutable(path, binary); err != nil {
+		return err
+	}
+	for _, name := range activeTool.Companions(version) {
+		companion := filepath.Join(filepath.Dir(path), name)
+		if !CheckFileExist(companion) {
+			Report.Warn("no %s next to %s, %s %s is imported without it", name, path, activeTool.Name, version)
+			continue
+		}
+		if err := copyExecutable(companion, CompanionBinary(dir, name, version)); err != nil {
+			return err
+		}
+	}
+	if sourceURL == "" {
+		sourceURL = "file://" + path
+	}
+	return RecordInstall(dir, version, sourceURL, archiveSHA256, "")
+}
+
+// copyExecutable : copy src to dest with mode 0755, through a temporary file renamed once complete
+func copyExecutable(src string, dest string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	Log.Debugf("copy %s -> %s", src, dest)
+	out, err := os.OpenFile(dest+".tmp", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0755)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(dest+".tmp", 0755)
+	}
+	if err != nil {
+		os.Remove(dest + ".tmp")
+		return err
+	}
+	return os.Rename(dest+".tmp", dest)
+}
+
+// downloadFile : download url to path
+func downloadFile(url string, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	Log.Infof("Downloading %s", url)
+	err = httpGet(url, f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// Unverifiable : whether the binary comes from a source that builds it itself
+func (c ImportCandidate) Unverifiable() bool {
+	return c.unverifiable
+}
@@ -0,0 +1,153 @@
+package lib_test
+
+import (
+	"compress/gzip"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tokiwong/helm-switcher/lib"
+	"github.com/tokiwong/helm-switcher/modal"
+)
+
+// writeFakeHelm : an executable printing output like helm version --client --short
+func writeFakeHelm(t *testing.T, path string, output string) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, []byte("#!/bin/sh\necho '"+output+"'\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestDiscoverBinaries : binaries of the sources are found once, those of the store left out
+func TestDiscoverBinaries(t *testing.T) {
+
+	home, err := ioutil.TempDir("", "helmswitch-import")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+
+	os.Setenv("ASDF_DATA_DIR", filepath.Join(home, ".asdf"))
+	defer os.Unsetenv("ASDF_DATA_DIR")
+
+	asdf := filepath.Join(home, ".asdf", "installs", "helm", "3.3.0", "bin", "helm")
+	writeFakeHelm(t, asdf, "v3.3.0+g8a4aeec")
+	writeFakeHelm(t, filepath.Join(home, ".helmenv", "versions", "2.16.9", "helm"), "Client: v2.16.9+g8ad7037")
+	ioutil.WriteFile(filepath.Join(home, ".helmenv", "versions", "2.16.9", "README"), []byte("not helm"), 0644)
+
+	/* a symlink to a binary found already, and one into the store */
+	store := filepath.Join(home, ".helm.versions")
+	writeFakeHelm(t, filepath.Join(store, "helm_3.2.4"), "v3.2.4")
+	os.MkdirAll(filepath.Join(home, ".helmenv", "versions", "3.3.0"), 0755)
+	os.Symlink(asdf, filepath.Join(home, ".helmenv", "versions", "3.3.0", "helm"))
+	os.MkdirAll(filepath.Join(home, ".helmenv", "versions", "3.2.4"), 0755)
+	os.Symlink(filepath.Join(store, "helm_3.2.4"), filepath.Join(home, ".helmenv", "versions", "3.2.4", "helm"))
+
+	candidates := lib.DiscoverBinaries(lib.ImportSources(home), store)
+	if len(candidates) == 2 && candidates[0].Source == "asdf" && candidates[0].Path == asdf && candidates[1].Source == "helmenv" {
+		t.Logf("Found %v [expected]", candidates)
+	} else {
+		t.Errorf("Unexpected candidates %v [unexpected]", candidates)
+	}
+
+	for _, c := range candidates {
+		version, err := lib.BinaryVersion(c.Path)
+		if err == nil && (version == "3.3.0" || version == "2.16.9") {
+			t.Logf("%s is %s [expected]", c.Path, version)
+		} else {
+			t.Errorf("Unexpected version %q %v [unexpected]", version, err)
+		}
+	}
+}
+
+// TestAdoptBinary : the binary and the tiller next to it are copied into the store and recorded
+func TestAdoptBinary(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "helmswitch-import")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	source := filepath.Join(dir, "asdf", "bin")
+	store := filepath.Join(dir, "store") + string(os.PathSeparator)
+	os.MkdirAll(store, 0755)
+	writeFakeHelm(t, filepath.Join(source, "helm"), "Client: v2.16.9+g8ad7037")
+	writeFakeHelm(t, filepath.Join(source, "tiller"), "v2.16.9")
+
+	if err := lib.AdoptBinary(store, filepath.Join(source, "helm"), "2.16.9", "", ""); err != nil {
+		t.Fatal(err)
+	}
+	if lib.CheckFileExist(filepath.Join(store, "helm_2.16.9")) && lib.CheckFileExist(filepath.Join(store, "tiller_2.16.9")) {
+		t.Log("helm and tiller adopted [expected]")
+	} else {
+		t.Error("Binaries not adopted [unexpected]")
+	}
+	if lib.CheckFileExist(filepath.Join(source, "helm")) {
+		t.Log("Original kept [expected]")
+	} else {
+		t.Error("Original removed [unexpected]")
+	}
+
+	state, _ := lib.LoadState(store)
+	if v, ok := state.Versions["2.16.9"]; ok && v.SourceURL == "file://"+filepath.Join(source, "helm") && v.BinarySHA256 != "" {
+		t.Logf("Recorded from %s [expected]", v.SourceURL)
+	} else {
+		t.Errorf("Unexpected state %+v [unexpected]", state.Versions)
+	}
+}
+
+// TestVerifyBinary : the binary is compared with the one of the published archive
+func TestVerifyBinary(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "helmswitch-import")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	archive := filepath.Join(dir, "helm-v3.3.0-linux-amd64.tar.gz")
+	writeTestTar(t, archive, "published helm", func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) })
+	content, _ := ioutil.ReadFile(archive)
+	sum := fmt.Sprintf("%x", sha256.Sum256(content))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/helm-v3.3.0-linux-amd64.tar.gz":
+			w.Write(content)
+		case "/helm-v3.3.0-linux-amd64.tar.gz.sha256":
+			fmt.Fprintf(w, "%s  helm-v3.3.0-linux-amd64.tar.gz\n", sum)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	release := modal.Repo{TagName: "v3.3.0", Assets: []modal.Assets{
+		{Name: "helm-v3.3.0-linux-amd64.tar.gz", BrowserDownloadURL: server.URL + "/helm-v3.3.0-linux-amd64.tar.gz"},
+	}}
+
+	same := filepath.Join(dir, "same")
+	ioutil.WriteFile(same, []byte("published helm"), 0755)
+	if url, archiveSum, err := lib.VerifyBinary(same, "3.3.0", release, "linux", "amd64"); err == nil && archiveSum == sum && url == server.URL+"/helm-v3.3.0-linux-amd64.tar.gz" {
+		t.Log("Binary verified [expected]")
+	} else {
+		t.Errorf("Unexpected %q %q %v [unexpected]", url, archiveSum, err)
+	}
+
+	patched := filepath.Join(dir, "patched")
+	ioutil.WriteFile(patched, []byte("patched helm"), 0755)
+	if _, _, err := lib.VerifyBinary(patched, "3.3.0", release, "linux", "amd64"); err == lib.ErrChecksumMismatch {
+		t.Logf("Modified binary: %v [expected]", err)
+	} else {
+		t.Errorf("Unexpected %v [unexpected]", err)
+	}
+}
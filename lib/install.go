@@ -1,23 +1,21 @@
 package lib
 
 import (
-	"fmt"
-	"log"
+	"io/ioutil"
 	"os"
-	"os/user"
+	"path/filepath"
 	"regexp"
 	"runtime"
+	"strings"
+	"time"
 
 	"github.com/tokiwong/helm-switcher/modal"
 )
 
 const (
-	helmURL        = "https://get.helm.sh/"
-	installFile    = "helm"
-	installVersion = "helm_"
-	binLocation    = "/usr/local/bin/helm"
-	installPath    = "/.helm.versions/"
-	recentFile     = "RECENT"
+	binLocation = "/usr/local/bin/helm"
+	installPath = "/.helm.versions/"
+	recentFile  = "RECENT"
 )
 
 var (
@@ -25,39 +23,6 @@ var (
 	installedBinPath = "/tmp"
 )
 
-func init() {
-	/* get current user */
-	usr, errCurr := user.Current()
-	if errCurr != nil {
-		log.Fatal(errCurr)
-	}
-
-	/* set installation location */
-	installLocation = usr.HomeDir + installPath
-
-	/* set default binary path for helm */
-	installedBinPath = binLocation
-
-	/* find helm binary location if helm is already installed*/
-	cmd := NewCommand("helm")
-	next := cmd.Find()
-
-	/* overrride installation default binary path if helm is already installed */
-	/* find the last bin path */
-	for path := next(); len(path) > 0; path = next() {
-		installedBinPath = path
-	}
-
-	/* remove current symlink if exist*/
-	symlinkExist := CheckSymlink(installedBinPath)
-
-	if symlinkExist {
-		RemoveSymlink(installedBinPath)
-	}
-	/* Create local installation directory if it does not exist */
-	CreateDirIfNotExist(installLocation)
-}
-
 //Install : Install the provided version in the argument
 func Install(url string, appversion string, assets []modal.Repo, userBinPath *string) string {
 
@@ -70,9 +35,7 @@ func Install(url string, appversion string, assets []modal.Repo, userBinPath *st
 	binDirExist := CheckDirExist(pathDir) //check bin path exist
 
 	if !binDirExist {
-		fmt.Printf("Binary path does not exist: %s\n", pathDir)
-		fmt.Printf("Please create binary path: %s for helm installation\n", pathDir)
-		os.Exit(1)
+		Fail("Binary path does not exist: %s\nPlease create binary path: %s for %s installation", pathDir, pathDir, activeTool.Name)
 	}
 
 	/* check if selected version already downloaded */
@@ -93,141 +56,195 @@ func Install(url string, appversion string, assets []modal.Repo, userBinPath *st
 	// 	return installLocation
 	// }
 
-	/* remove current symlink if exist*/
-	symlinkExist := CheckSymlink(installedBinPath)
-
-	if symlinkExist {
-		RemoveSymlink(installedBinPath)
-	}
-
 	/* if selected version already exist, */
 	/* proceed to download it from the helm release page */
 	//url := helmURL + "v" + helmversion + "/" + "helm" + "_" + goos + "_" + goarch
 
 	goarch := runtime.GOARCH
 	goos := runtime.GOOS
-	urlDownload := ""
-	chkDownload := ""
-
-	for _, v := range assets {
-
-		if v.TagName == "v"+appversion {
-			if len(v.Assets) > 0 {
-				for _, b := range v.Assets {
-
-					matchedOS, _ := regexp.MatchString(goos, b.BrowserDownloadURL)
-					matchedARCH, _ := regexp.MatchString(goarch, b.BrowserDownloadURL)
-					if matchedOS && matchedARCH {
-						// urlDownload = b.BrowserDownloadURL
-						urlDownload = "https://get.helm.sh/helm-" + v.TagName + "-" + goos + "-" + goarch + ".tar.gz"
-						chkDownload = urlDownload + ".sha256"
-						break
-					}
-				}
-			}
+
+	var release *modal.Repo
+	for i, v := range assets {
+		if ReleaseVersion(v) == appversion {
+			release = &assets[i]
 			break
 		}
 	}
-
-	fileInstalled, _ := DownloadFromURL(installLocation, urlDownload)
-	tarRead, readErr := os.Open(fileInstalled)
-	if readErr != nil {
-		fmt.Println("Expected a location, found " + fileInstalled)
+	if release == nil {
+		Fail("%s %s is not a release of %s", activeTool.Name, appversion, activeTool.Repo)
 	}
 
-	chkInstalled, _ := DownloadFromURL(installLocation, chkDownload)
-	verifySha := VerifyChecksum(fileInstalled, chkInstalled)
-	if verifySha != true {
-		log.Fatal("didn't pass the verify step")
+	urlDownload, err := activeTool.ArtifactURL(*release, goos, goarch)
+	if err != nil {
+		Fail("%v", err)
+	}
+	chkDownload, err := activeTool.ChecksumURL(*release, goos, goarch)
+	if err != nil {
+		Fail("%v", err)
 	}
 
-	/* untar the downloaded file*/
-	Untar(installLocation, tarRead)
-	binDir := installLocation + "/" + goos + "-" + goarch + "/helm"
-
-	/* rename file to helm version name - helm_x.x.x */
-	RenameFile(binDir, installLocation+installVersion+appversion)
-
-	err := os.Chmod(installLocation+installVersion+appversion, 0755)
+	fileInstalled, err := DownloadFromURL(installLocation, urlDownload)
 	if err != nil {
-		log.Println(err)
+		Fail("Unable to download %s %s: %v", activeTool.Name, appversion, err)
 	}
+	downloads := []string{fileInstalled}
 
-	/* set symlink to desired version */
-	CreateSymlink(installLocation+installVersion+appversion, installedBinPath)
-	fmt.Printf("Switched helm to version %q \n", appversion)
-	return installLocation
-}
+	if chkDownload != "" {
+		chkInstalled, err := DownloadFromURL(installLocation, chkDownload)
+		if err != nil {
+			removeDownloads(downloads)
+			Fail("Unable to download the checksum of %s %s: %v", activeTool.Name, appversion, err)
+		}
+		downloads = append(downloads, chkInstalled)
+		if err := VerifyChecksum(fileInstalled, chkInstalled, activeTool.ChecksumFormat); err != nil {
+			removeDownloads(downloads)
+			Fail("didn't pass the verify step: %v", err)
+		}
+	} else {
+		Report.Warn("%s publishes no checksum, %s cannot be verified", activeTool.Name, urlDownload)
+	}
+	archiveSha, _ := FileChecksum(fileInstalled)
 
-// AddRecent : add to recent file
-func AddRecent(requestedVersion string, installLocation string) {
+	binPath, err := activeTool.BinaryPath(appversion, goos, goarch)
+	if err != nil {
+		removeDownloads(downloads)
+		Fail("%v", err)
+	}
 
-	semverRegex := regexp.MustCompile(`\d+(\.\d+){2}\z`)
+	/* extract the binary only, in a dir of its own removed once done; Fail exits, so remove it before */
+	extractDir, err := ioutil.TempDir(installLocation, extractDirPrefix)
+	if err != nil {
+		removeDownloads(downloads)
+		Fail("%v", err)
+	}
 
-	fileExist := CheckFileExist(installLocation + recentFile)
-	if fileExist {
-		lines, errRead := ReadLines(installLocation + recentFile)
+	/* along with the companions of this version, eg. tiller for Helm 2 */
+	companions := activeTool.Companions(appversion)
+	wanted := []string{binPath}
+	for _, name := range companions {
+		wanted = append(wanted, CompanionPath(binPath, name))
+	}
 
-		if errRead != nil {
-			fmt.Printf("Error: %s\n", errRead)
-			return
-		}
+	layout, err := ExtractArchive(fileInstalled, extractDir, activeTool.Layout, wanted)
+	if err != nil {
+		os.RemoveAll(extractDir)
+		removeDownloads(downloads)
+		Fail("Unable to extract %s: %v", fileInstalled, err)
+	}
 
-		for _, line := range lines {
-			if !semverRegex.MatchString(line) {
-				RemoveFiles(installLocation + recentFile)
-				CreateRecentFile(requestedVersion)
-				return
+	binary := installLocation + activeTool.Prefix() + appversion
+	if layout == LayoutBinary {
+		/* the download is the binary itself */
+		RenameFile(fileInstalled, binary)
+	} else {
+		/* rename file to versioned name - helm_x.x.x */
+		RenameFile(filepath.Join(extractDir, binPath), binary)
+
+		for _, name := range companions {
+			companion := CompanionBinary(installLocation, name, appversion)
+			RenameFile(filepath.Join(extractDir, CompanionPath(binPath, name)), companion)
+			if err := os.Chmod(companion, 0755); err != nil {
+				Report.Warn("%v", err)
 			}
 		}
+	}
 
-		versionExist := VersionExist(requestedVersion, lines)
+	/* the archive, its checksum and the extraction dir are not needed once the binary is out */
+	Log.Debugf("remove %s", extractDir)
+	os.RemoveAll(extractDir)
+	removeDownloads(downloads)
+	if !CheckFileExist(binary) {
+		Fail("Unable to install %s %s, the active version is left as it is", activeTool.Name, appversion)
+	}
 
-		if !versionExist {
-			if len(lines) >= 3 {
-				_, lines = lines[len(lines)-1], lines[:len(lines)-1]
+	Log.Debugf("chmod 0755 %s", binary)
+	err = os.Chmod(binary, 0755)
+	if err != nil {
+		Report.Warn("%v", err)
+	}
 
-				lines = append([]string{requestedVersion}, lines...)
-				WriteLines(lines, installLocation+recentFile)
-			} else {
-				lines = append([]string{requestedVersion}, lines...)
-				WriteLines(lines, installLocation+recentFile)
-			}
-		}
+	/* keep the checksums so the install can be verified later */
+	if err := RecordInstall(installLocation, appversion, urlDownload, archiveSha, installedBinPath); err != nil {
+		Report.Warn("unable to record checksum: %v", err)
+	}
 
-	} else {
-		CreateRecentFile(requestedVersion)
+	/* remove current symlink if exist, now that the new binary is in place */
+	if CheckSymlink(installedBinPath) {
+		RemoveSymlink(installedBinPath)
+	}
+
+	/* set symlink to desired version */
+	CreateSymlink(binary, installedBinPath)
+	Log.Infof("Switched %s to version %q ", activeTool.Name, appversion)
+	if err := LinkCompanions(installLocation, appversion, installedBinPath); err != nil {
+		Report.Warn("%v", err)
 	}
+
+	Report.Action = "installed"
+	Report.Version = appversion
+	Report.Path = binary
+	return installLocation
 }
 
-// GetRecentVersions : get recent version from file
-func GetRecentVersions() ([]string, error) {
+// removeDownloads : delete the archive and checksum of an install, once extracted or when the install failed
+func removeDownloads(downloads []string) {
+	for _, download := range downloads {
+		Log.Debugf("remove %s", download)
+		/* a binary download has been renamed to the installed binary */
+		if err := os.Remove(download); err != nil && !os.IsNotExist(err) {
+			Report.Warn("%v", err)
+		}
+	}
+}
 
-	fileExist := CheckFileExist(installLocation + recentFile)
-	if fileExist {
-		semverRegex := regexp.MustCompile(`\A\d+(\.\d+){2}\z`)
+// GetRecentVersions : up to n versions from the switch history, most used and most recent first
+func GetRecentVersions(n int) ([]string, error) {
+	entries, err := LoadHistory(installLocation)
+	if err != nil {
+		return nil, err
+	}
+	return RecentVersions(entries, n, time.Now()), nil
+}
 
-		lines, errRead := ReadLines(installLocation + recentFile)
+// scanInstalledVersions : versions of the active tool stored as helm_x.x.x in dir, newest first
+func scanInstalledVersions(dir string) []string {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
 
-		if errRead != nil {
-			fmt.Printf("Error: %s\n", errRead)
-			return nil, errRead
+	semvers := []*Version{}
+	for _, f := range files {
+		name := f.Name()
+		if f.IsDir() || !strings.HasPrefix(name, activeTool.Prefix()) {
+			continue
 		}
-
-		for _, line := range lines {
-			if !semverRegex.MatchString(line) {
-				RemoveFiles(installLocation + recentFile)
-				return nil, errRead
-			}
+		sv, err := NewVersion(strings.TrimPrefix(name, activeTool.Prefix()))
+		if err != nil {
+			continue
 		}
-		return lines, nil
+		semvers = append(semvers, sv)
 	}
-	return nil, nil
+	Sort(semvers)
+
+	versions := []string{}
+	for _, sv := range semvers {
+		versions = append(versions, sv.String())
+	}
+	return versions
 }
 
-//CreateRecentFile : create a recent file
-func CreateRecentFile(requestedVersion string) {
-	WriteLines([]string{requestedVersion}, installLocation+recentFile)
+// ActiveVersion : version the symlink at binPath points to in dir, empty if it is not managed
+func ActiveVersion(binPath string, dir string) string {
+	if !isManagedLink(binPath, dir) {
+		return ""
+	}
+	target, _ := os.Readlink(binPath)
+	name := filepath.Base(target)
+	if !strings.HasPrefix(name, activeTool.Prefix()) {
+		return ""
+	}
+	return strings.TrimPrefix(name, activeTool.Prefix())
 }
 
 // ValidVersionFormat : returns valid version format
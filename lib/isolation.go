@@ -0,0 +1,147 @@
+package lib
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+)
+
+// How the helm homes are shared between versions
+const (
+	/* every version uses the homes of the environment */
+	IsolationNone = "none"
+	/* one home per major version, Helm 2 and Helm 3 never share plugins or repositories */
+	IsolationMajor = "major"
+	/* one home per version */
+	IsolationVersion = "version"
+)
+
+/* directory of the store holding the isolated homes */
+const homesDir = "homes"
+
+/* homes of helm 3, named as in its HELM_*_HOME and XDG_*_HOME variables */
+const (
+	helmConfigHome = "CONFIG"
+	helmDataHome   = "DATA"
+)
+
+// HomeDir : the isolated home of version in dir, eg. homes/v3 or homes/3.3.0, empty without isolation
+func HomeDir(dir string, version string, isolation string) (string, error) {
+	switch isolation {
+	case IsolationNone, "":
+		return "", nil
+	case IsolationMajor:
+		sv, err := NewVersion(version)
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(dir, homesDir, "v"+strconv.FormatInt(sv.Major, 10)), nil
+	case IsolationVersion:
+		return filepath.Join(dir, homesDir, version), nil
+	}
+	return "", fmt.Errorf("unknown isolation %q, expecting %s, %s or %s", isolation, IsolationNone, IsolationMajor, IsolationVersion)
+}
+
+// HomeEnv : the environment pointing helm version at its isolated home, creating it,
+// HELM_HOME for Helm 2 and the HELM_*_HOME dirs for Helm 3
+func HomeEnv(dir string, version string, isolation string) (map[string]string, error) {
+	env := map[string]string{}
+	if activeTool.Name != "helm" {
+		return env, nil
+	}
+
+	home, err := HomeDir(dir, version, isolation)
+	if err != nil || home == "" {
+		return env, err
+	}
+	sv, err := NewVersion(version)
+	if err != nil {
+		return env, err
+	}
+
+	if sv.Major < 3 {
+		env["HELM_HOME"] = home
+	} else {
+		env["HELM_CONFIG_HOME"] = filepath.Join(home, "config")
+		env["HELM_CACHE_HOME"] = filepath.Join(home, "cache")
+		env["HELM_DATA_HOME"] = filepath.Join(home, "data")
+	}
+	for _, path := range env {
+		Log.Debugf("mkdir %s", path)
+		if err := os.MkdirAll(path, 0755); err != nil {
+			return env, err
+		}
+	}
+	return env, nil
+}
+
+// EnvList : env as sorted NAME=value entries, appended to base
+func EnvList(base []string, env map[string]string) []string {
+	names := []string{}
+	for name := range env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	list := append([]string{}, base...)
+	for _, name := range names {
+		list = append(list, name+"="+env[name])
+	}
+	return list
+}
+
+// helmHome : the home helm version reads kind from, with env overriding the environment,
+// and whether it is the single $HELM_HOME of Helm 2
+func helmHome(version string, kind string, env map[string]string) (string, bool, error) {
+	sv, err := NewVersion(version)
+	if err != nil {
+		return "", false, err
+	}
+	getenv := envLookup(env)
+	usr, err := user.Current()
+	if err != nil {
+		return "", false, err
+	}
+
+	if sv.Major < 3 {
+		if home := getenv("HELM_HOME"); home != "" {
+			return home, true, nil
+		}
+		return filepath.Join(usr.HomeDir, ".helm"), true, nil
+	}
+
+	/* helm 3 follows XDG, with the platform defaults of helm.sh/helm/v3/pkg/helmpath */
+	if home := getenv("HELM_" + kind + "_HOME"); home != "" {
+		return home, false, nil
+	}
+	if xdg := getenv("XDG_" + kind + "_HOME"); xdg != "" {
+		return filepath.Join(xdg, "helm"), false, nil
+	}
+	switch runtime.GOOS {
+	case "darwin":
+		if kind == helmConfigHome {
+			return filepath.Join(usr.HomeDir, "Library", "Preferences", "helm"), false, nil
+		}
+		return filepath.Join(usr.HomeDir, "Library", "helm"), false, nil
+	case "windows":
+		return filepath.Join(getenv("APPDATA"), "helm"), false, nil
+	}
+	if kind == helmConfigHome {
+		return filepath.Join(usr.HomeDir, ".config", "helm"), false, nil
+	}
+	return filepath.Join(usr.HomeDir, ".local", "share", "helm"), false, nil
+}
+
+// envLookup : getenv with env overriding the environment
+func envLookup(env map[string]string) func(string) string {
+	return func(name string) string {
+		if value, ok := env[name]; ok {
+			return value
+		}
+		return os.Getenv(name)
+	}
+}
@@ -0,0 +1,147 @@
+package lib
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path"
+	"path/filepath"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// ContextRule : versions of a tool, helm by default, to use with the kube contexts matching a glob
+type ContextRule struct {
+	Tool     string `yaml:"tool" json:"tool"`
+	Context  string `yaml:"context" json:"context"`
+	Versions string `yaml:"versions" json:"versions"`
+
+	constraint *Constraint
+}
+
+// Check : whether version may be used with the context of the rule
+func (r ContextRule) Check(version string) bool {
+	return r.constraint != nil && r.constraint.Check(version)
+}
+
+// ParseContextRules : check the rules of the config, filling in their defaults
+func ParseContextRules(rules []ContextRule) error {
+	for i := range rules {
+		rule := &rules[i]
+		if rule.Tool == "" {
+			rule.Tool = "helm"
+		}
+		if _, err := path.Match(rule.Context, ""); err != nil || rule.Context == "" {
+			return fmt.Errorf("context rule %d: invalid context pattern %q", i+1, rule.Context)
+		}
+		constraint, err := ParseConstraint(rule.Versions)
+		if err != nil {
+			return fmt.Errorf("context rule %d: %v", i+1, err)
+		}
+		rule.constraint = constraint
+	}
+	return nil
+}
+
+// MatchContext : the first rule of the active tool whose pattern matches context
+func MatchContext(rules []ContextRule, context string) (ContextRule, bool) {
+	for _, rule := range rules {
+		if rule.Tool != activeTool.Name {
+			continue
+		}
+		if ok, _ := path.Match(rule.Context, context); ok {
+			return rule, true
+		}
+	}
+	return ContextRule{}, false
+}
+
+// KubeconfigFiles : the files of $KUBECONFIG, ~/.kube/config when it is not set
+func KubeconfigFiles() []string {
+	if kubeconfig := os.Getenv("KUBECONFIG"); kubeconfig != "" {
+		files := []string{}
+		for _, file := range filepath.SplitList(kubeconfig) {
+			if file != "" {
+				files = append(files, file)
+			}
+		}
+		return files
+	}
+	usr, err := user.Current()
+	if err != nil {
+		return nil
+	}
+	return []string{filepath.Join(usr.HomeDir, ".kube", "config")}
+}
+
+// CurrentContext : the current-context of the kubeconfig files, the first file setting it wins as with kubectl
+func CurrentContext(files []string) (string, string, error) {
+	for _, file := range files {
+		content, err := ioutil.ReadFile(file)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return "", "", err
+		}
+
+		var kubeconfig struct {
+			CurrentContext string `yaml:"current-context"`
+		}
+		if err := yaml.Unmarshal(content, &kubeconfig); err != nil {
+			return "", "", fmt.Errorf("%s: %v", file, err)
+		}
+		if kubeconfig.CurrentContext != "" {
+			Log.Debugf("current context %s from %s", kubeconfig.CurrentContext, file)
+			return kubeconfig.CurrentContext, file, nil
+		}
+	}
+	return "", "", nil
+}
+
+// ContextFlag : the context given to helm with --kube-context in args, the last one wins; a bare -- ends the flags
+func ContextFlag(args []string) (string, bool) {
+	context, ok := "", false
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "--":
+			return context, ok
+		case args[i] == "--kube-context" && i+1 < len(args):
+			context, ok = args[i+1], true
+			i++
+		case strings.HasPrefix(args[i], "--kube-context="):
+			context, ok = strings.TrimPrefix(args[i], "--kube-context="), true
+		}
+	}
+	return context, ok
+}
+
+// SelectedContext : the kube context helm runs against, with where it was read from: --kube-context in args,
+// else $HELM_KUBECONTEXT, else the current-context of the kubeconfig files
+func SelectedContext(args []string, files []string) (string, string, error) {
+	if context, ok := ContextFlag(args); ok {
+		return context, "--kube-context", nil
+	}
+	if context := os.Getenv("HELM_KUBECONTEXT"); context != "" {
+		return context, "$HELM_KUBECONTEXT", nil
+	}
+	return CurrentContext(files)
+}
+
+// NewestMatching : the newest of versions the rule allows
+func NewestMatching(rule ContextRule, versions []string) (string, bool) {
+	newest := ""
+	var newestVersion *Version
+	for _, v := range versions {
+		sv, err := NewVersion(v)
+		if err != nil || !rule.Check(v) {
+			continue
+		}
+		if newestVersion == nil || newestVersion.LessThan(*sv) {
+			newest, newestVersion = v, sv
+		}
+	}
+	return newest, newest != ""
+}
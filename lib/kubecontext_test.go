@@ -0,0 +1,121 @@
+package lib_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tokiwong/helm-switcher/lib"
+)
+
+// TestMatchContext : the first rule whose glob matches the context wins
+func TestMatchContext(t *testing.T) {
+
+	rules := []lib.ContextRule{
+		{Context: "legacy-*", Versions: "< 3.0.0"},
+		{Context: "*", Versions: ">= 3.2.0"},
+	}
+	if err := lib.ParseContextRules(rules); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]string{"legacy-eu": "< 3.0.0", "prod-us": ">= 3.2.0"}
+	for context, versions := range cases {
+		if rule, ok := lib.MatchContext(rules, context); ok && rule.Versions == versions {
+			t.Logf("%s requires %s [expected]", context, versions)
+		} else {
+			t.Errorf("Unexpected rule %v for %s [unexpected]", rule, context)
+		}
+	}
+
+	rule, _ := lib.MatchContext(rules, "legacy-eu")
+	if version, ok := lib.NewestMatching(rule, []string{"3.3.0", "2.16.9", "2.17.0", "2.9.1"}); ok && version == "2.17.0" {
+		t.Log("Newest Helm 2 picked [expected]")
+	} else {
+		t.Errorf("Unexpected version %q [unexpected]", version)
+	}
+	if _, ok := lib.NewestMatching(rule, []string{"3.3.0"}); !ok {
+		t.Log("No installed Helm 2 [expected]")
+	} else {
+		t.Error("Helm 3 picked for a legacy context [unexpected]")
+	}
+
+	for _, invalid := range [][]lib.ContextRule{{{Context: "[", Versions: "3.x"}}, {{Context: "prod", Versions: "three"}}} {
+		if err := lib.ParseContextRules(invalid); err != nil {
+			t.Logf("Invalid rule: %v [expected]", err)
+		} else {
+			t.Errorf("Invalid rule %v accepted [unexpected]", invalid)
+		}
+	}
+}
+
+// TestCurrentContext : the first kubeconfig file setting current-context wins
+func TestCurrentContext(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "helmswitch-kube")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	clusters := filepath.Join(dir, "clusters")
+	ioutil.WriteFile(clusters, []byte("apiVersion: v1\nkind: Config\nclusters: []\n"), 0644)
+	main := filepath.Join(dir, "config")
+	ioutil.WriteFile(main, []byte("apiVersion: v1\nkind: Config\ncurrent-context: legacy-eu\n"), 0644)
+
+	files := []string{filepath.Join(dir, "missing"), clusters, main}
+	if context, file, err := lib.CurrentContext(files); err == nil && context == "legacy-eu" && file == main {
+		t.Logf("Context %s from %s [expected]", context, file)
+	} else {
+		t.Errorf("Unexpected context %q %q %v [unexpected]", context, file, err)
+	}
+
+	os.Setenv("KUBECONFIG", clusters+string(os.PathListSeparator)+main)
+	defer os.Unsetenv("KUBECONFIG")
+	if files := lib.KubeconfigFiles(); len(files) == 2 && files[1] == main {
+		t.Log("Files from KUBECONFIG [expected]")
+	} else {
+		t.Errorf("Unexpected files %v [unexpected]", files)
+	}
+}
+
+// TestSelectedContext : --kube-context wins over $HELM_KUBECONTEXT, which wins over the kubeconfig
+func TestSelectedContext(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "helmswitch-kube")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	config := filepath.Join(dir, "config")
+	ioutil.WriteFile(config, []byte("apiVersion: v1\nkind: Config\ncurrent-context: prod\n"), 0644)
+	files := []string{config}
+
+	defer os.Setenv("HELM_KUBECONTEXT", os.Getenv("HELM_KUBECONTEXT"))
+	os.Unsetenv("HELM_KUBECONTEXT")
+
+	cases := []struct {
+		args    []string
+		env     string
+		context string
+		source  string
+	}{
+		{[]string{"list"}, "", "prod", config},
+		{[]string{"--kube-context", "legacy", "list"}, "", "legacy", "--kube-context"},
+		{[]string{"list", "--kube-context=legacy-eu"}, "staging", "legacy-eu", "--kube-context"},
+		{[]string{"list"}, "staging", "staging", "$HELM_KUBECONTEXT"},
+		/* after a bare --, the flag belongs to a plugin */
+		{[]string{"secrets", "--", "--kube-context", "legacy"}, "", "prod", config},
+	}
+	for _, c := range cases {
+		os.Setenv("HELM_KUBECONTEXT", c.env)
+		context, source, err := lib.SelectedContext(c.args, files)
+		if err == nil && context == c.context && source == c.source {
+			t.Logf("%v: %s from %s [expected]", c.args, context, source)
+		} else {
+			t.Errorf("%v: unexpected %q %q %v [unexpected]", c.args, context, source, err)
+		}
+	}
+}
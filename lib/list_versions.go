@@ -6,10 +6,8 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
-	"log"
 	"net/http"
 	"net/url"
-	"os"
 	"reflect"
 	"regexp"
 	"sort"
@@ -37,20 +35,22 @@ func GetAppList(appURL string, client *modal.Client) ([]string, []modal.Repo) {
 	v.Set("clientID", client.ClientID)
 	v.Add("clientSecret", client.ClientSecret)
 
-	gswitch := http.Client{
-		Timeout: time.Second * 10, // Maximum of 10 secs [decresing this seem to fail]
-	}
+	gswitch := NewHTTPClient(time.Second * 10) // Maximum of 10 secs [decresing this seem to fail]
 
 	apiURL := appURL + v.Encode()
 
 	req, err := http.NewRequest(http.MethodGet, apiURL, nil)
 	if err != nil {
-		log.Fatal("Unable to make request. Please try again.")
+		Fail("Unable to make request. Please try again.")
 	}
 
 	req.Header.Set("User-Agent", "App Installer")
 
-	resp, _ := gswitch.Do(req)
+	resp, errResp := gswitch.Do(req)
+	if errResp != nil {
+		Fail("Unable to make request: %v", errResp)
+	}
+	LogResponse(resp)
 	links := resp.Header.Get("Link")
 	link := strings.Split(links, ",")
 
@@ -59,8 +59,7 @@ func GetAppList(appURL string, client *modal.Client) ([]string, []modal.Repo) {
 			strPage := inBetween(pagNum, "page=", ">")
 			page, err := strconv.Atoi(strPage)
 			if err != nil {
-				fmt.Println(err)
-				os.Exit(2)
+				Fail("%v", err)
 			}
 			numPages = page
 		}
@@ -69,33 +68,28 @@ func GetAppList(appURL string, client *modal.Client) ([]string, []modal.Repo) {
 	applist, assets := getAppVersion(appURL, numPages, client)
 
 	if len(applist) == 40 {
-		for header, v := range resp.Header {
-			switch header {
-			case "X-Ratelimit-Remaining":
-				fmt.Print("API Requests Remaining")
-				fmt.Print(" : ")
-				fmt.Println(v)
-			case "X-Ratelimit-Reset":
-				fmt.Print("Your Rate Limit will reset at")
-				fmt.Print(" : ")
-				epochTime, err := strconv.Atoi(strings.Join(v, " "))
-				if err != nil {
-					panic(err)
-				}
-				resetTime := time.Unix(int64(epochTime), 0)
-				fmt.Println(resetTime)
-				log.Fatal()
-			}
-	       
-		}
-		log.Fatal("Unable to get release from repo, please try again later")
-		os.Exit(1)
-		
+		printRateLimit(resp.Header)
+		Fail("Unable to get release from repo, please try again later")
 	}
 
 	return applist, assets
 }
 
+// printRateLimit : explain the github api rate limit when a request was refused
+func printRateLimit(header http.Header) {
+	if remaining := header.Get("X-Ratelimit-Remaining"); remaining != "" {
+		Log.Errorf("API Requests Remaining : %s", remaining)
+	}
+	if reset := header.Get("X-Ratelimit-Reset"); reset != "" {
+		epochTime, err := strconv.Atoi(reset)
+		if err != nil {
+			Log.Errorf("%v", err)
+			return
+		}
+		Log.Errorf("Your Rate Limit will reset at : %v", time.Unix(int64(epochTime), 0))
+	}
+}
+
 //VersionExist : check if requested version exist
 func VersionExist(val interface{}, array interface{}) (exists bool) {
 
@@ -182,12 +176,11 @@ func getAppVersion(appURL string, numPages int, client *modal.Client) ([]string,
 	var sortedVersion []string
 
 	for _, v := range assets {
-		semverRegex := regexp.MustCompile(`\Av\d+(\.\d+){2}\z`)
-		if semverRegex.MatchString(v.TagName) {
-			trimstr := strings.Trim(v.TagName, "v")
+		if trimstr, ok := activeTool.TagVersion(v.TagName); ok {
 			sv, err := NewVersion(trimstr)
 			if err != nil {
-				fmt.Println(err)
+				Log.Warnf("%v", err)
+				continue
 			}
 			semvers = append(semvers, sv)
 		}
@@ -205,59 +198,39 @@ func getAppVersion(appURL string, numPages int, client *modal.Client) ([]string,
 func getAppBody(helmURLPage string, ch chan<- *[]modal.Repo) {
 	defer wg.Done()
 
-	gswitch := http.Client{
-		Timeout: time.Second * 10, // Maximum of 10 secs [decresing this seem to fail]
-	}
+	gswitch := NewHTTPClient(time.Second * 10) // Maximum of 10 secs [decresing this seem to fail]
 
 	req, err := http.NewRequest(http.MethodGet, helmURLPage, nil)
 	if err != nil {
-		log.Fatal("Unable to make request. Please try again.")
+		Fail("Unable to make request. Please try again.")
 	}
 
 	req.Header.Set("User-Agent", "github-appinstaller")
 
 	res, getErr := gswitch.Do(req)
 	if getErr != nil {
-		log.Fatal("Unable to make request Please try again.")
+		Fail("Unable to make request Please try again.")
 	}
+	defer res.Body.Close()
+	LogResponse(res)
 
 	body, readErr := ioutil.ReadAll(res.Body)
 	if readErr != nil {
-		log.Fatal("Unable to get release from repo ")
-		log.Fatal(readErr)
+		Fail("Unable to get release from repo: %v", readErr)
 	}
 
 	var repo []modal.Repo
 	jsonErr := json.Unmarshal(body, &repo)
 	if jsonErr != nil {
-		for header, v := range res.Header {
-			switch header {
-			case "X-Ratelimit-Remaining":
-				fmt.Print("API Requests Remaining")
-				fmt.Print(" : ")
-				fmt.Println(v)
-			case "X-Ratelimit-Reset":
-				fmt.Print("Your Rate Limit will reset at")
-				fmt.Print(" : ")
-				epochTime, err := strconv.Atoi(strings.Join(v, " "))
-				if err != nil {
-					panic(err)
-				}
-				resetTime := time.Unix(int64(epochTime), 0)
-				fmt.Println(resetTime)
-			}
-	       
-		}
-		log.Fatal("Unable to get release from repo ")
-		log.Fatal(jsonErr)
+		printRateLimit(res.Header)
+		Fail("Unable to get release from repo: %v", jsonErr)
 	}
 
 	var validRepo []modal.Repo
 
 	for _, num := range repo {
 		if num.Prerelease == false && num.Draft == false {
-			semverRegex := regexp.MustCompile(`\Av\d+(\.\d+){2}\z`)
-			if semverRegex.MatchString(num.TagName) {
+			if _, ok := activeTool.TagVersion(num.TagName); ok {
 				validRepo = append(validRepo, num)
 			}
 		}
@@ -0,0 +1,68 @@
+package lib
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// Level : verbosity of the logger
+type Level int
+
+const (
+	// LevelQuiet : only errors are printed
+	LevelQuiet Level = iota
+	// LevelInfo : progress and warnings, the default
+	LevelInfo
+	// LevelVerbose : adds download sizes, checksums and other details
+	LevelVerbose
+	// LevelDebug : adds http requests, rate limits, redirects and file operations
+	LevelDebug
+)
+
+// Logger : leveled logger for human readable messages
+type Logger struct {
+	Level Level
+	Out   io.Writer
+	Err   io.Writer
+}
+
+// Log : logger used by every command
+var Log = NewLogger(os.Stdout, LevelInfo)
+
+// NewLogger : create a logger writing to out at the given level
+func NewLogger(out io.Writer, level Level) *Logger {
+	return &Logger{Level: level, Out: out, Err: os.Stderr}
+}
+
+func (l *Logger) logf(level Level, prefix string, format string, a ...interface{}) {
+	if l.Level < level {
+		return
+	}
+	fmt.Fprintf(l.Out, prefix+format+"\n", a...)
+}
+
+// Infof : print progress messages, hidden with --quiet
+func (l *Logger) Infof(format string, a ...interface{}) {
+	l.logf(LevelInfo, "", format, a...)
+}
+
+// Warnf : print a warning, hidden with --quiet
+func (l *Logger) Warnf(format string, a ...interface{}) {
+	l.logf(LevelInfo, "Warning: ", format, a...)
+}
+
+// Verbosef : print details shown with --verbose
+func (l *Logger) Verbosef(format string, a ...interface{}) {
+	l.logf(LevelVerbose, "", format, a...)
+}
+
+// Debugf : print diagnostics shown with --debug
+func (l *Logger) Debugf(format string, a ...interface{}) {
+	l.logf(LevelDebug, "[debug] ", format, a...)
+}
+
+// Errorf : print an error, always shown
+func (l *Logger) Errorf(format string, a ...interface{}) {
+	fmt.Fprintf(l.Err, format+"\n", a...)
+}
@@ -0,0 +1,76 @@
+package lib_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/tokiwong/helm-switcher/lib"
+)
+
+// TestLoggerLevels : messages above the logger level should not be printed
+func TestLoggerLevels(t *testing.T) {
+
+	var out, errOut bytes.Buffer
+	logger := lib.NewLogger(&out, lib.LevelInfo)
+	logger.Err = &errOut
+
+	logger.Infof("info %d", 1)
+	logger.Verbosef("verbose %d", 2)
+	logger.Debugf("debug %d", 3)
+	logger.Errorf("error %d", 4)
+
+	if strings.Contains(out.String(), "info 1") {
+		t.Log("Info message printed [expected]")
+	} else {
+		t.Error("Info message missing [unexpected]")
+	}
+
+	if strings.Contains(out.String(), "verbose 2") || strings.Contains(out.String(), "debug 3") {
+		t.Errorf("Verbose or debug message printed at info level [unexpected]: %q", out.String())
+	} else {
+		t.Log("Verbose and debug messages hidden [expected]")
+	}
+
+	if strings.Contains(errOut.String(), "error 4") {
+		t.Log("Error message printed [expected]")
+	} else {
+		t.Error("Error message missing [unexpected]")
+	}
+}
+
+// TestLoggerQuiet : quiet level should hide everything but errors
+func TestLoggerQuiet(t *testing.T) {
+
+	var out, errOut bytes.Buffer
+	logger := lib.NewLogger(&out, lib.LevelQuiet)
+	logger.Err = &errOut
+
+	logger.Infof("Downloading ...")
+	logger.Warnf("careful")
+	logger.Errorf("failed")
+
+	if out.Len() == 0 {
+		t.Log("Nothing printed in quiet mode [expected]")
+	} else {
+		t.Errorf("Quiet mode printed %q [unexpected]", out.String())
+	}
+
+	if strings.Contains(errOut.String(), "failed") {
+		t.Log("Error message printed in quiet mode [expected]")
+	} else {
+		t.Error("Error message missing in quiet mode [unexpected]")
+	}
+}
+
+// TestRedactURL : client secret should never end up in debug logs
+func TestRedactURL(t *testing.T) {
+
+	redacted := lib.RedactURL("https://api.github.com/repos/helm/helm/releases?clientID=id&clientSecret=secret&page=2")
+
+	if strings.Contains(redacted, "secret&") || strings.HasSuffix(redacted, "=secret") {
+		t.Errorf("Secret still present in %v [unexpected]", redacted)
+	} else {
+		t.Logf("Secret redacted %v [expected]", redacted)
+	}
+}
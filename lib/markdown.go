@@ -0,0 +1,87 @@
+package lib
+
+import (
+	"regexp"
+	"strings"
+)
+
+/* terminal escapes used by the markdown renderer */
+const (
+	ansiReset     = "\033[0m"
+	ansiBold      = "\033[1m"
+	ansiFaint     = "\033[2m"
+	ansiUnderline = "\033[4m"
+	ansiCyan      = "\033[36m"
+)
+
+var (
+	mdHeading = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	mdBullet  = regexp.MustCompile(`^(\s*)[-*+]\s+(.*)$`)
+	mdRule    = regexp.MustCompile(`^\s*([-*_]\s*){3,}$`)
+	mdLink    = regexp.MustCompile(`\[([^\]]+)\]\(([^)]+)\)`)
+	mdBold    = regexp.MustCompile(`\*\*([^*]+)\*\*|__([^_]+)__`)
+	mdCode    = regexp.MustCompile("`([^`]+)`")
+	mdComment = regexp.MustCompile(`(?s)<!--.*?-->`)
+)
+
+// RenderMarkdown : render a GitHub release body for the terminal, with colors when color is set
+func RenderMarkdown(body string, color bool) string {
+	style := func(s string, codes ...string) string {
+		if !color {
+			return s
+		}
+		return strings.Join(codes, "") + s + ansiReset
+	}
+
+	body = mdComment.ReplaceAllString(strings.Replace(body, "\r\n", "\n", -1), "")
+
+	var out strings.Builder
+	fenced := false
+	for _, line := range strings.Split(body, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			fenced = !fenced
+			continue
+		}
+		if fenced {
+			out.WriteString("    " + style(line, ansiFaint) + "\n")
+			continue
+		}
+
+		switch {
+		case mdHeading.MatchString(line):
+			m := mdHeading.FindStringSubmatch(line)
+			text := renderInline(m[2], style)
+			if len(m[1]) <= 2 {
+				out.WriteString(style(text, ansiBold, ansiUnderline) + "\n")
+			} else {
+				out.WriteString(style(text, ansiBold) + "\n")
+			}
+		case mdRule.MatchString(line):
+			out.WriteString(style(strings.Repeat("─", 40), ansiFaint) + "\n")
+		case mdBullet.MatchString(line):
+			m := mdBullet.FindStringSubmatch(line)
+			out.WriteString(m[1] + "  • " + renderInline(m[2], style) + "\n")
+		default:
+			out.WriteString(renderInline(line, style) + "\n")
+		}
+	}
+	return strings.TrimRight(out.String(), "\n") + "\n"
+}
+
+// renderInline : links, bold and code spans within a line
+func renderInline(line string, style func(string, ...string) string) string {
+	line = mdCode.ReplaceAllStringFunc(line, func(s string) string {
+		return style(mdCode.FindStringSubmatch(s)[1], ansiCyan)
+	})
+	line = mdBold.ReplaceAllStringFunc(line, func(s string) string {
+		m := mdBold.FindStringSubmatch(s)
+		return style(m[1]+m[2], ansiBold)
+	})
+	return mdLink.ReplaceAllStringFunc(line, func(s string) string {
+		m := mdLink.FindStringSubmatch(s)
+		if m[1] == m[2] {
+			return style(m[2], ansiUnderline)
+		}
+		return m[1] + " (" + style(m[2], ansiUnderline) + ")"
+	})
+}
@@ -0,0 +1,26 @@
+package lib_test
+
+import (
+	"testing"
+
+	"github.com/tokiwong/helm-switcher/lib"
+)
+
+// TestRenderMarkdown : release bodies are readable without colors
+func TestRenderMarkdown(t *testing.T) {
+
+	body := "## Changelog\r\n<!-- hidden -->\r\n- **fix** `helm lint` crash\r\n* see [docs](https://helm.sh)\r\n```\r\nhelm upgrade\r\n```"
+	expected := "Changelog\n\n  • fix helm lint crash\n  • see docs (https://helm.sh)\n    helm upgrade\n"
+
+	if out := lib.RenderMarkdown(body, false); out == expected {
+		t.Log("Markdown rendered [expected]")
+	} else {
+		t.Errorf("Unexpected rendering %q [unexpected]", out)
+	}
+
+	if out := lib.RenderMarkdown("# Title", true); out == "\033[1m\033[4mTitle\033[0m\n" {
+		t.Log("Headings styled on a terminal [expected]")
+	} else {
+		t.Errorf("Unexpected rendering %q [unexpected]", out)
+	}
+}
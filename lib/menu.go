@@ -0,0 +1,152 @@
+package lib
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/tokiwong/helm-switcher/modal"
+)
+
+// MenuItem : a version in the interactive menu with what is known about it
+type MenuItem struct {
+	Version   string
+	Group     string
+	Name      string
+	Installed bool
+	Active    bool
+	Recent    bool
+	Pinned    bool
+	Published time.Time
+	/* what the policy says about the version */
+	Policy       string
+	PolicyReason string
+	LastUsedAt   time.Time
+}
+
+// Markers : short state flags shown next to the version
+func (i MenuItem) Markers() string {
+	markers := []string{}
+	if i.Active {
+		markers = append(markers, "active")
+	}
+	if i.Pinned {
+		markers = append(markers, "pinned")
+	}
+	if i.Installed {
+		markers = append(markers, "installed")
+	}
+	if i.Recent {
+		markers = append(markers, "recent")
+	}
+	switch i.Policy {
+	case PolicyRefuse:
+		markers = append(markers, "refused")
+	case PolicyWarn:
+		markers = append(markers, "deprecated")
+	}
+	return strings.Join(markers, " ")
+}
+
+// PublishedDate : release date, empty when unknown
+func (i MenuItem) PublishedDate() string {
+	if i.Published.IsZero() {
+		return ""
+	}
+	return i.Published.Local().Format("2006-01-02")
+}
+
+// LastUsed : when the version was last switched to, empty when never
+func (i MenuItem) LastUsed() string {
+	if i.LastUsedAt.IsZero() {
+		return ""
+	}
+	return i.LastUsedAt.Local().Format("2006-01-02 15:04")
+}
+
+// BuildMenu : recent versions first, then every version grouped by major, newest first
+func BuildMenu(versions []string, releases []modal.Repo, state *State, recent []string, active string, pinned string) []MenuItem {
+
+	published := map[string]modal.Repo{}
+	for _, r := range releases {
+		published[ReleaseVersion(r)] = r
+	}
+
+	isRecent := map[string]bool{}
+	for _, v := range recent {
+		isRecent[v] = true
+	}
+
+	newItem := func(version string, group string) MenuItem {
+		item := MenuItem{
+			Version: version,
+			Group:   group,
+			Active:  version == active,
+			Pinned:  version == pinned,
+			Recent:  isRecent[version],
+		}
+		if r, ok := published[version]; ok {
+			item.Published = r.PublishedAt
+			item.Name = r.Name
+		}
+		if state != nil {
+			if v, ok := state.Versions[version]; ok {
+				item.Installed = true
+				item.LastUsedAt = v.LastUsedAt
+			}
+		}
+		return item
+	}
+
+	items := []MenuItem{}
+	seen := map[string]bool{}
+	for _, v := range recent {
+		items = append(items, newItem(v, "recent"))
+		seen[v] = true
+	}
+
+	/* versions the remote list does not know about, eg. when offline, still show up */
+	all := append([]string{}, versions...)
+	if state != nil {
+		all = RemoveDuplicateVersions(append(all, state.Installed()...))
+	}
+
+	semvers := []*Version{}
+	for _, v := range all {
+		if sv, err := NewVersion(v); err == nil && !seen[v] {
+			semvers = append(semvers, sv)
+		}
+	}
+	Sort(semvers)
+
+	for _, sv := range semvers {
+		items = append(items, newItem(sv.String(), fmt.Sprintf("%s %d", activeTool.Name, sv.Major)))
+	}
+	return items
+}
+
+// MatchMenuItem : every word typed must match the version, the group or a marker
+func MatchMenuItem(input string, item MenuItem) bool {
+	haystack := strings.ToLower(item.Version + " " + item.Group + " " + item.Markers() + " " + item.PublishedDate())
+	for _, word := range strings.Fields(strings.ToLower(input)) {
+		if !strings.Contains(haystack, strings.TrimPrefix(word, "v")) {
+			return false
+		}
+	}
+	return true
+}
+
+// MenuCursor : index of the pinned version, or the active one, to start the menu on
+func MenuCursor(items []MenuItem) int {
+	for i, item := range items {
+		if item.Pinned {
+			return i
+		}
+	}
+	for i, item := range items {
+		if item.Active {
+			return i
+		}
+	}
+	return 0
+}
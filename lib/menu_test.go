@@ -0,0 +1,77 @@
+package lib_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/tokiwong/helm-switcher/lib"
+	"github.com/tokiwong/helm-switcher/modal"
+)
+
+// TestBuildMenu : recent versions first, then grouped by major, with state markers
+func TestBuildMenu(t *testing.T) {
+
+	published := time.Date(2020, 8, 10, 0, 0, 0, 0, time.UTC)
+	releases := []modal.Repo{{TagName: "v3.3.0", Name: "Helm v3.3.0", PublishedAt: published}}
+	state := &lib.State{Versions: map[string]*lib.InstalledVersion{
+		"2.16.1": {Version: "2.16.1"},
+	}}
+
+	items := lib.BuildMenu([]string{"3.3.0", "3.2.4", "2.16.1"}, releases, state, []string{"2.16.1"}, "2.16.1", "3.2.4")
+
+	versions := []string{}
+	for _, item := range items {
+		versions = append(versions, item.Group+" "+item.Version)
+	}
+	expected := []string{"recent 2.16.1", "helm 3 3.3.0", "helm 3 3.2.4"}
+
+	if len(versions) != len(expected) {
+		t.Fatalf("Unexpected menu %v [unexpected]", versions)
+	}
+	for i := range expected {
+		if versions[i] != expected[i] {
+			t.Errorf("Unexpected menu %v, expected %v [unexpected]", versions, expected)
+			break
+		}
+	}
+
+	if items[0].Markers() == "active installed recent" {
+		t.Logf("Markers %q [expected]", items[0].Markers())
+	} else {
+		t.Errorf("Unexpected markers %q [unexpected]", items[0].Markers())
+	}
+
+	if items[1].PublishedDate() != "" && items[1].Name == "Helm v3.3.0" {
+		t.Log("Release details attached [expected]")
+	} else {
+		t.Error("Release details missing [unexpected]")
+	}
+
+	if cursor := lib.MenuCursor(items); cursor == 2 {
+		t.Log("Menu starts on the pinned version [expected]")
+	} else {
+		t.Errorf("Menu starts on %d [unexpected]", cursor)
+	}
+}
+
+// TestMatchMenuItem : typed words filter on version, group and markers
+func TestMatchMenuItem(t *testing.T) {
+
+	item := lib.MenuItem{Version: "3.3.0", Group: "helm 3", Installed: true}
+
+	for _, input := range []string{"3.3", "v3.3.0", "installed", "helm 3 inst", ""} {
+		if lib.MatchMenuItem(input, item) {
+			t.Logf("%q matches [expected]", input)
+		} else {
+			t.Errorf("%q should match [unexpected]", input)
+		}
+	}
+
+	for _, input := range []string{"2.16", "active", "3.3 recent"} {
+		if !lib.MatchMenuItem(input, item) {
+			t.Logf("%q does not match [expected]", input)
+		} else {
+			t.Errorf("%q should not match [unexpected]", input)
+		}
+	}
+}
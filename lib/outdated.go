@@ -0,0 +1,54 @@
+package lib
+
+// Outdated : newest releases above a version, by kind of upgrade; empty when there is none
+type Outdated struct {
+	Current string `json:"current"`
+	Source  string `json:"source"`
+	Patch   string `json:"patch,omitempty"`
+	Minor   string `json:"minor,omitempty"`
+	Major   string `json:"major,omitempty"`
+}
+
+// CheckOutdated : the newest patch, minor and major releases of versions newer than current
+func CheckOutdated(current string, versions []string) (Outdated, error) {
+	result := Outdated{Current: current}
+
+	cv, err := NewVersion(current)
+	if err != nil {
+		return result, err
+	}
+
+	var patch, minor, major *Version
+	newest := func(best *Version, candidate *Version) *Version {
+		if best == nil || best.Compare(*candidate) < 0 {
+			return candidate
+		}
+		return best
+	}
+
+	for _, v := range versions {
+		sv, err := NewVersion(v)
+		if err != nil || sv.PreRelease != "" || sv.Compare(*cv) <= 0 {
+			continue
+		}
+		switch {
+		case sv.Major > cv.Major:
+			major = newest(major, sv)
+		case sv.Minor > cv.Minor:
+			minor = newest(minor, sv)
+		default:
+			patch = newest(patch, sv)
+		}
+	}
+
+	if patch != nil {
+		result.Patch = patch.String()
+	}
+	if minor != nil {
+		result.Minor = minor.String()
+	}
+	if major != nil {
+		result.Major = major.String()
+	}
+	return result, nil
+}
@@ -0,0 +1,43 @@
+package lib_test
+
+import (
+	"testing"
+
+	"github.com/tokiwong/helm-switcher/lib"
+)
+
+// TestCheckOutdated : newest patch, minor and major releases are reported separately
+func TestCheckOutdated(t *testing.T) {
+
+	versions := []string{"3.3.1", "3.3.0", "3.2.4", "3.2.1", "3.2.0", "2.16.10", "4.0.0-rc.1"}
+
+	result, err := lib.CheckOutdated("3.2.1", versions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Patch == "3.2.4" && result.Minor == "3.3.1" && result.Major == "" {
+		t.Logf("Outdated %+v [expected]", result)
+	} else {
+		t.Errorf("Unexpected result %+v [unexpected]", result)
+	}
+
+	result, _ = lib.CheckOutdated("2.16.10", versions)
+	if result.Patch == "" && result.Minor == "" && result.Major == "3.3.1" {
+		t.Logf("Only a major upgrade for %s [expected]", result.Current)
+	} else {
+		t.Errorf("Unexpected result %+v [unexpected]", result)
+	}
+
+	result, _ = lib.CheckOutdated("3.3.1", versions)
+	if result.Patch == "" && result.Minor == "" && result.Major == "" {
+		t.Log("Latest version is up to date, pre-releases ignored [expected]")
+	} else {
+		t.Errorf("Unexpected result %+v [unexpected]", result)
+	}
+
+	if _, err := lib.CheckOutdated("latest", versions); err != nil {
+		t.Logf("Invalid version refused: %v [expected]", err)
+	} else {
+		t.Error("Invalid version accepted [unexpected]")
+	}
+}
@@ -0,0 +1,80 @@
+package lib
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// JSONOutput : print a single structured result on stdout when the command ends
+var JSONOutput = false
+
+// Report : result of the command currently running
+var Report = NewResult("")
+
+// Result : structured outcome of a command
+type Result struct {
+	Command  string      `json:"command"`
+	Action   string      `json:"action"`
+	Version  string      `json:"version,omitempty"`
+	Path     string      `json:"path,omitempty"`
+	Checksum string      `json:"checksum,omitempty"`
+	Duration string      `json:"duration"`
+	Warnings []string    `json:"warnings"`
+	Data     interface{} `json:"data,omitempty"`
+	Error    string      `json:"error,omitempty"`
+
+	start time.Time
+}
+
+// NewResult : start tracking the outcome of a command
+func NewResult(command string) *Result {
+	return &Result{
+		Command:  command,
+		Action:   "none",
+		Warnings: []string{},
+		start:    time.Now(),
+	}
+}
+
+// Warn : record a warning on the result and print it for humans
+func (r *Result) Warn(format string, a ...interface{}) {
+	msg := fmt.Sprintf(format, a...)
+	r.Warnings = append(r.Warnings, msg)
+	Log.Warnf("%s", msg)
+}
+
+// Write : write the result as JSON
+func (r *Result) Write(w io.Writer) error {
+	r.Duration = time.Since(r.start).String()
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// EnableJSONOutput : send human readable messages to stderr and print the result as JSON on exit
+func EnableJSONOutput() {
+	JSONOutput = true
+	Log.Out = os.Stderr
+}
+
+// Exit : print the result if JSON output is enabled and exit with the given code
+func Exit(code int) {
+	if JSONOutput {
+		if err := Report.Write(os.Stdout); err != nil {
+			Log.Errorf("%v", err)
+		}
+	}
+	os.Exit(code)
+}
+
+// Fail : record the error on the result, print it and exit non-zero
+func Fail(format string, a ...interface{}) {
+	msg := fmt.Sprintf(format, a...)
+	Report.Error = msg
+	Log.Errorf("%s", msg)
+	Exit(1)
+}
@@ -0,0 +1,168 @@
+package lib_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/tokiwong/helm-switcher/lib"
+)
+
+// TestResultWrite : the result should be written with the field names of the --output json contract
+func TestResultWrite(t *testing.T) {
+
+	r := lib.NewResult("switch")
+	r.Action = "switched"
+	r.Version = "3.3.0"
+	r.Path = "/tmp/helm_3.3.0"
+
+	var out bytes.Buffer
+	if err := r.Write(&out); err != nil {
+		t.Fatal(err)
+	}
+
+	fields := map[string]interface{}{}
+	if err := json.Unmarshal(out.Bytes(), &fields); err != nil {
+		t.Fatalf("Result is not JSON [unexpected]: %v", err)
+	}
+
+	for key, want := range map[string]string{"command": "switch", "action": "switched", "version": "3.3.0", "path": "/tmp/helm_3.3.0"} {
+		if fields[key] == want {
+			t.Logf("Field %s is %q [expected]", key, want)
+		} else {
+			t.Errorf("Field %s is %v, expected %q [unexpected]", key, fields[key], want)
+		}
+	}
+
+	if _, ok := fields["duration"].(string); ok {
+		t.Log("Duration written [expected]")
+	} else {
+		t.Error("Duration missing [unexpected]")
+	}
+
+	/* warnings is always a list, so that consumers do not check for null */
+	if warnings, ok := fields["warnings"].([]interface{}); ok && len(warnings) == 0 {
+		t.Log("Empty warnings written as [] [expected]")
+	} else {
+		t.Errorf("Unexpected warnings %v [unexpected]", fields["warnings"])
+	}
+
+	for _, key := range []string{"error", "checksum", "data"} {
+		if _, ok := fields[key]; ok {
+			t.Errorf("Empty field %s written [unexpected]", key)
+		} else {
+			t.Logf("Empty field %s omitted [expected]", key)
+		}
+	}
+
+	r.Error = "Not a valid helm version"
+	out.Reset()
+	r.Write(&out)
+	if strings.Contains(out.String(), `"error": "Not a valid helm version"`) {
+		t.Log("Error written [expected]")
+	} else {
+		t.Errorf("Error missing from %s [unexpected]", out.String())
+	}
+}
+
+// TestResultWarn : a warning should be recorded on the result and printed through the logger
+func TestResultWarn(t *testing.T) {
+
+	out, level := lib.Log.Out, lib.Log.Level
+	defer func() { lib.Log.Out, lib.Log.Level = out, level }()
+
+	var printed bytes.Buffer
+	lib.Log.Out = &printed
+	lib.Log.Level = lib.LevelInfo
+
+	r := lib.NewResult("doctor")
+	r.Warn("%s has no recorded checksum", "3.3.0")
+
+	if len(r.Warnings) == 1 && r.Warnings[0] == "3.3.0 has no recorded checksum" {
+		t.Logf("Warning recorded %v [expected]", r.Warnings)
+	} else {
+		t.Errorf("Unexpected warnings %v [unexpected]", r.Warnings)
+	}
+
+	if printed.String() == "Warning: 3.3.0 has no recorded checksum\n" {
+		t.Log("Warning printed [expected]")
+	} else {
+		t.Errorf("Unexpected output %q [unexpected]", printed.String())
+	}
+
+	/* --quiet hides the message, the result still has it */
+	printed.Reset()
+	lib.Log.Level = lib.LevelQuiet
+	r.Warn("careful")
+	if printed.Len() == 0 && len(r.Warnings) == 2 {
+		t.Log("Quiet warning recorded but not printed [expected]")
+	} else {
+		t.Errorf("Quiet warning printed %q or not recorded %v [unexpected]", printed.String(), r.Warnings)
+	}
+}
+
+// TestEnableJSONOutput : human readable messages should move to stderr, leaving stdout to the result
+func TestEnableJSONOutput(t *testing.T) {
+
+	out, jsonOutput := lib.Log.Out, lib.JSONOutput
+	defer func() { lib.Log.Out, lib.JSONOutput = out, jsonOutput }()
+
+	lib.Log.Out = os.Stdout
+	lib.EnableJSONOutput()
+
+	if lib.JSONOutput {
+		t.Log("JSON output enabled [expected]")
+	} else {
+		t.Error("JSON output not enabled [unexpected]")
+	}
+
+	if lib.Log.Out == os.Stderr && lib.Log.Err == os.Stderr {
+		t.Log("Messages sent to stderr [expected]")
+	} else {
+		t.Error("Messages still sent to stdout [unexpected]")
+	}
+}
+
+// TestFailJSON : a failure should exit 1 with the result on stdout and the message on stderr
+func TestFailJSON(t *testing.T) {
+
+	/* Fail exits, run it in a child process */
+	if os.Getenv("HELMSWITCH_TEST_FAIL") == "1" {
+		lib.EnableJSONOutput()
+		lib.Report = lib.NewResult("switch")
+		lib.Report.Warn("careful")
+		lib.Fail("Not a valid %s version", "helm")
+		return
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(os.Args[0], "-test.run=TestFailJSON")
+	cmd.Env = append(os.Environ(), "HELMSWITCH_TEST_FAIL=1")
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	err := cmd.Run()
+
+	if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
+		t.Log("Exited with code 1 [expected]")
+	} else {
+		t.Errorf("Unexpected exit %v [unexpected]", err)
+	}
+
+	result := lib.Result{}
+	if err := json.Unmarshal(stdout.Bytes(), &result); err != nil {
+		t.Fatalf("stdout is not only the result [unexpected]: %q", stdout.String())
+	}
+	if result.Command == "switch" && result.Error == "Not a valid helm version" && len(result.Warnings) == 1 {
+		t.Logf("Result %+v on stdout [expected]", result)
+	} else {
+		t.Errorf("Unexpected result %+v [unexpected]", result)
+	}
+
+	if strings.Contains(stderr.String(), "Warning: careful") && strings.Contains(stderr.String(), "Not a valid helm version") {
+		t.Log("Warning and error printed on stderr [expected]")
+	} else {
+		t.Errorf("Unexpected stderr %q [unexpected]", stderr.String())
+	}
+}
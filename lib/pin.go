@@ -0,0 +1,133 @@
+package lib
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// toolVersionsFile : the asdf file pinning the versions of several tools, one per line
+const toolVersionsFile = ".tool-versions"
+
+// Files helmswitch pin writes
+const (
+	/* .helm-version, holding the version alone */
+	PinFormatVersionFile = "version-file"
+	/* the line of the tool in the asdf .tool-versions */
+	PinFormatToolVersions = "tool-versions"
+)
+
+// PinFile : file pinning the version of the active tool in a project, eg. .helm-version
+func PinFile() string {
+	return "." + activeTool.Name + "-version"
+}
+
+// FindPinnedVersion : version in the nearest .helm-version, or helm line of .tool-versions, from dir up to
+// the root, with the file it came from; in the same dir .helm-version wins
+func FindPinnedVersion(dir string) (string, string) {
+	for {
+		path := filepath.Join(dir, PinFile())
+		if content, err := ioutil.ReadFile(path); err == nil {
+			version := strings.TrimPrefix(strings.TrimSpace(string(content)), "v")
+			if ValidVersionFormat(version) {
+				Log.Debugf("pinned version %s from %s", version, path)
+				return version, path
+			}
+			Report.Warn("ignoring invalid version %q in %s", version, path)
+		}
+
+		path = filepath.Join(dir, toolVersionsFile)
+		if content, err := ioutil.ReadFile(path); err == nil {
+			if version, ok := parseToolVersions(string(content), path); ok {
+				Log.Debugf("pinned version %s from %s", version, path)
+				return version, path
+			}
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", ""
+		}
+		dir = parent
+	}
+}
+
+// parseToolVersions : the first release version listed for the active tool in a .tool-versions,
+// asdf's system, ref: and path: versions are not releases and skipped
+func parseToolVersions(content string, path string) (string, bool) {
+	for _, line := range strings.Split(content, "\n") {
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 || fields[0] != activeTool.Name {
+			continue
+		}
+		for _, version := range fields[1:] {
+			version = strings.TrimPrefix(version, "v")
+			if ValidVersionFormat(version) {
+				return version, true
+			}
+		}
+		Log.Verbosef("no %s release in %s: %s", activeTool.Name, path, strings.Join(fields[1:], " "))
+		return "", false
+	}
+	return "", false
+}
+
+// WritePin : pin version of the active tool in dir, in .helm-version or .tool-versions, and the file written
+func WritePin(dir string, version string, format string) (string, error) {
+	if !ValidVersionFormat(version) {
+		return "", fmt.Errorf("invalid version %q", version)
+	}
+
+	var path, content string
+	switch format {
+	case PinFormatVersionFile, "":
+		path = filepath.Join(dir, PinFile())
+		content = version + "\n"
+	case PinFormatToolVersions:
+		path = filepath.Join(dir, toolVersionsFile)
+		existing, err := ioutil.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			return "", err
+		}
+		content = setToolVersion(string(existing), activeTool.Name, version)
+	default:
+		return "", fmt.Errorf("unknown pin format %q, expecting %s or %s", format, PinFormatVersionFile, PinFormatToolVersions)
+	}
+
+	Log.Debugf("write %s", path)
+	if err := ioutil.WriteFile(path+".tmp", []byte(content), 0644); err != nil {
+		return "", err
+	}
+	return path, os.Rename(path+".tmp", path)
+}
+
+// setToolVersion : content of a .tool-versions with the line of tool set to version, the other lines kept
+func setToolVersion(content string, tool string, version string) string {
+	if content == "" {
+		return tool + " " + version + "\n"
+	}
+
+	lines := []string{}
+	found := false
+	for _, line := range strings.Split(strings.TrimSuffix(content, "\n"), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || fields[0] != tool {
+			lines = append(lines, line)
+			continue
+		}
+		/* a single line per tool, the first one is replaced and any other dropped */
+		if !found {
+			lines = append(lines, tool+" "+version)
+			found = true
+		}
+	}
+	if !found {
+		lines = append(lines, tool+" "+version)
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
@@ -0,0 +1,114 @@
+package lib_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tokiwong/helm-switcher/lib"
+)
+
+// TestFindPinnedVersion : the nearest .helm-version up the tree wins
+func TestFindPinnedVersion(t *testing.T) {
+
+	root, err := ioutil.TempDir("", "helmswitch-pin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	nested := filepath.Join(root, "charts", "app")
+	os.MkdirAll(nested, 0755)
+	ioutil.WriteFile(filepath.Join(root, ".helm-version"), []byte("v3.3.0\n"), 0644)
+
+	version, file := lib.FindPinnedVersion(nested)
+	if version == "3.3.0" && file == filepath.Join(root, ".helm-version") {
+		t.Logf("Pinned version %v from %v [expected]", version, file)
+	} else {
+		t.Errorf("Unexpected pin %q from %q [unexpected]", version, file)
+	}
+
+	ioutil.WriteFile(filepath.Join(nested, ".helm-version"), []byte("2.16.1"), 0644)
+
+	if version, _ := lib.FindPinnedVersion(nested); version == "2.16.1" {
+		t.Log("Nearest pin wins [expected]")
+	} else {
+		t.Errorf("Unexpected pin %q [unexpected]", version)
+	}
+}
+
+// TestToolVersions : the helm line of an asdf .tool-versions pins the version, .helm-version wins next to it
+func TestToolVersions(t *testing.T) {
+
+	root, err := ioutil.TempDir("", "helmswitch-pin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	nested := filepath.Join(root, "charts")
+	os.MkdirAll(nested, 0755)
+	toolVersions := filepath.Join(root, ".tool-versions")
+	ioutil.WriteFile(toolVersions, []byte("# tools\nterraform 0.13.0\nhelm system 3.2.4 # fallback\nkubectl 1.18.8\n"), 0644)
+
+	if version, file := lib.FindPinnedVersion(nested); version == "3.2.4" && file == toolVersions {
+		t.Logf("Pinned version %v from %v [expected]", version, file)
+	} else {
+		t.Errorf("Unexpected pin %q from %q [unexpected]", version, file)
+	}
+
+	ioutil.WriteFile(filepath.Join(root, ".helm-version"), []byte("3.3.0\n"), 0644)
+	if version, _ := lib.FindPinnedVersion(nested); version == "3.3.0" {
+		t.Log(".helm-version wins [expected]")
+	} else {
+		t.Errorf("Unexpected pin %q [unexpected]", version)
+	}
+}
+
+// TestWritePin : .helm-version holds the version alone, .tool-versions keeps the lines of the other tools
+func TestWritePin(t *testing.T) {
+
+	root, err := ioutil.TempDir("", "helmswitch-pin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	if file, err := lib.WritePin(root, "3.3.0", lib.PinFormatVersionFile); err == nil {
+		content, _ := ioutil.ReadFile(file)
+		if string(content) == "3.3.0\n" && filepath.Base(file) == ".helm-version" {
+			t.Log("Version file [expected]")
+		} else {
+			t.Errorf("Unexpected %s: %q [unexpected]", file, content)
+		}
+	} else {
+		t.Error(err)
+	}
+
+	toolVersions := filepath.Join(root, ".tool-versions")
+	if _, err := lib.WritePin(root, "3.3.0", lib.PinFormatToolVersions); err != nil {
+		t.Fatal(err)
+	}
+	if content, _ := ioutil.ReadFile(toolVersions); string(content) == "helm 3.3.0\n" {
+		t.Log("New .tool-versions [expected]")
+	} else {
+		t.Errorf("Unexpected .tool-versions %q [unexpected]", content)
+	}
+
+	ioutil.WriteFile(toolVersions, []byte("terraform 0.13.0\nhelm 3.2.4\nkubectl 1.18.8\nhelm 2.16.9\n"), 0644)
+	if _, err := lib.WritePin(root, "3.3.0", lib.PinFormatToolVersions); err != nil {
+		t.Fatal(err)
+	}
+	if content, _ := ioutil.ReadFile(toolVersions); string(content) == "terraform 0.13.0\nhelm 3.3.0\nkubectl 1.18.8\n" {
+		t.Log("Helm line replaced [expected]")
+	} else {
+		t.Errorf("Unexpected .tool-versions %q [unexpected]", content)
+	}
+
+	if _, err := lib.WritePin(root, "latest", lib.PinFormatToolVersions); err != nil {
+		t.Logf("Invalid version refused: %v [expected]", err)
+	} else {
+		t.Error("Invalid version pinned [unexpected]")
+	}
+}
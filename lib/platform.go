@@ -0,0 +1,170 @@
+package lib
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/tokiwong/helm-switcher/modal"
+)
+
+/* what follows the tool name in an artifact name, eg. -v3.3.0-linux-arm64.tar.gz.asc */
+var assetNameRegex = regexp.MustCompile(`\A[-_](?:v?(\d+\.\d+\.\d+(?:-[0-9A-Za-z.]+)?)[-_])?(darwin|linux|windows|freebsd|openbsd|netbsd)[-_]([a-z0-9_]+?)(\.tar\.gz|\.tgz|\.tar\.xz|\.tar\.bz2|\.zip|\.exe)?(\.asc)?\z`)
+
+/* the ARM revision of the cpu, from the kernel */
+var cpuArchRegex = regexp.MustCompile(`(?m)^CPU architecture\s*:\s*(\d+)`)
+
+// AssetName : a release asset parsed as the artifact of a tool for a platform
+type AssetName struct {
+	Name    string
+	Version string
+	OS      string
+	Arch    string
+	Ext     string
+	/* a signature of the artifact, which is published elsewhere, eg. helm-v3.3.0-linux-amd64.tar.gz.asc */
+	Signature bool
+	URL       string
+}
+
+// Platform : os/arch as named by the artifact
+func (a AssetName) Platform() string {
+	return a.OS + "/" + a.Arch
+}
+
+// ParseAssetName : the platform of an artifact of tool, false when name is not one, eg. a checksum file
+func ParseAssetName(tool string, name string) (AssetName, bool) {
+	if !strings.HasPrefix(name, tool) {
+		return AssetName{}, false
+	}
+	match := assetNameRegex.FindStringSubmatch(strings.TrimPrefix(name, tool))
+	if match == nil {
+		return AssetName{}, false
+	}
+	return AssetName{
+		Name:      name,
+		Version:   match[1],
+		OS:        match[2],
+		Arch:      match[3],
+		Ext:       match[4],
+		Signature: match[5] != "",
+	}, true
+}
+
+// PlatformArchs : names an artifact may use for goarch, the best first
+//
+// arm is split by revision: Helm's arm build runs on ARMv6 and up, so an ARMv5 cpu has no build.
+func PlatformArchs(goarch string, goarm string) []string {
+	switch goarch {
+	case "amd64":
+		return []string{"amd64", "x86_64"}
+	case "386":
+		return []string{"386", "i386", "x86"}
+	case "arm64":
+		return []string{"arm64", "aarch64"}
+	case "arm":
+		switch goarm {
+		case "5":
+			return []string{"armv5"}
+		case "6":
+			return []string{"armv6", "arm"}
+		default:
+			return []string{"armv7", "arm", "armv6"}
+		}
+	}
+	/* ppc64le, s390x and the others are named as Go names them */
+	return []string{goarch}
+}
+
+// armVersion : ARM revision of this machine, GOARM when set, 6 when it cannot be told
+func armVersion() string {
+	if goarm := os.Getenv("GOARM"); goarm != "" {
+		return goarm
+	}
+	if cpuinfo, err := ioutil.ReadFile("/proc/cpuinfo"); err == nil {
+		if match := cpuArchRegex.FindSubmatch(cpuinfo); match != nil {
+			if match[1][0] >= '7' {
+				return "7"
+			}
+			return string(match[1])
+		}
+	}
+	return "6"
+}
+
+// matchAsset : url of the artifact of release built for the platform, false when no asset of the release
+// is an artifact of the tool
+func (t *Tool) matchAsset(release modal.Repo, goos string, goarch string) (string, bool, error) {
+	artifacts := []AssetName{}
+	for _, asset := range release.Assets {
+		if a, ok := ParseAssetName(t.Name, asset.Name); ok {
+			a.URL = asset.BrowserDownloadURL
+			artifacts = append(artifacts, a)
+		}
+	}
+	if len(artifacts) == 0 {
+		return "", false, nil
+	}
+
+	osName := goos
+	if name, ok := t.OS[goos]; ok {
+		osName = name
+	}
+	archs := PlatformArchs(goarch, armVersion())
+	if name, ok := t.Arch[goarch]; ok {
+		archs = append([]string{name}, archs...)
+	}
+
+	/* an attached artifact is better than the signature of one published elsewhere */
+	for _, arch := range archs {
+		for _, signature := range []bool{false, true} {
+			for _, a := range artifacts {
+				if a.OS != osName || a.Arch != arch || a.Signature != signature {
+					continue
+				}
+				Log.Debugf("asset %s matches %s/%s", a.Name, goos, goarch)
+				if !signature {
+					return a.URL, true, nil
+				}
+				url, err := t.signedArtifactURL(release, a, goos, goarch)
+				return url, true, err
+			}
+		}
+	}
+
+	available := []string{}
+	for _, a := range artifacts {
+		available = append(available, a.Platform())
+	}
+	sort.Strings(available)
+	return "", true, fmt.Errorf("%s %s has no build for this platform (%s/%s), builds are published for %s",
+		t.Name, release.TagName, goos, goarch, strings.Join(uniqueStrings(available), ", "))
+}
+
+// signedArtifactURL : the artifact a signature asset is for, in the directory of the url template
+//
+// Helm attaches only the .asc files to its GitHub releases and serves the artifacts from get.helm.sh.
+func (t *Tool) signedArtifactURL(release modal.Repo, a AssetName, goos string, goarch string) (string, error) {
+	if t.URL == "" {
+		return "", fmt.Errorf("%s %s publishes only the signature %s, and no url to download the artifact from", t.Name, release.TagName, a.Name)
+	}
+	version, _ := t.TagVersion(release.TagName)
+	url, err := t.render("url", t.URL, version, goos, goarch, "")
+	if err != nil {
+		return "", err
+	}
+	return url[:strings.LastIndex(url, "/")+1] + strings.TrimSuffix(a.Name, ".asc"), nil
+}
+
+// uniqueStrings : sorted values without their duplicates
+func uniqueStrings(values []string) []string {
+	unique := []string{}
+	for i, v := range values {
+		if i == 0 || v != values[i-1] {
+			unique = append(unique, v)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,119 @@
+package lib_test
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/tokiwong/helm-switcher/lib"
+	"github.com/tokiwong/helm-switcher/modal"
+)
+
+// TestParseAssetName : artifacts of a tool are parsed, checksums and other tools are not
+func TestParseAssetName(t *testing.T) {
+
+	cases := []struct {
+		tool, name, os, arch, ext string
+		signature                 bool
+	}{
+		{"helm", "helm-v3.3.0-linux-arm64.tar.gz.asc", "linux", "arm64", ".tar.gz", true},
+		{"helm", "helm-v3.3.0-windows-amd64.zip", "windows", "amd64", ".zip", false},
+		{"helm", "helm-v3.3.0-linux-ppc64le.tar.gz", "linux", "ppc64le", ".tar.gz", false},
+		{"kustomize", "kustomize_v3.8.1_darwin_amd64.tar.gz", "darwin", "amd64", ".tar.gz", false},
+		{"helmfile", "helmfile_linux_386", "linux", "386", "", false},
+		{"tool", "tool-1.0.0-linux-x86_64.tar.gz", "linux", "x86_64", ".tar.gz", false},
+	}
+	for _, c := range cases {
+		a, ok := lib.ParseAssetName(c.tool, c.name)
+		if ok && a.OS == c.os && a.Arch == c.arch && a.Ext == c.ext && a.Signature == c.signature {
+			t.Logf("%s is %s [expected]", c.name, a.Platform())
+		} else {
+			t.Errorf("Unexpected %s: %+v %v [unexpected]", c.name, a, ok)
+		}
+	}
+
+	for _, name := range []string{"helm-v3.3.0-linux-amd64.tar.gz.sha256", "checksums.txt", "tiller-v2.16.9-linux-amd64.tar.gz"} {
+		if _, ok := lib.ParseAssetName("helm", name); !ok {
+			t.Logf("%s skipped [expected]", name)
+		} else {
+			t.Errorf("%s parsed [unexpected]", name)
+		}
+	}
+}
+
+// TestPlatformArchs : arm is matched by revision and never picks arm64
+func TestPlatformArchs(t *testing.T) {
+
+	cases := map[string][]string{
+		"amd64/":   {"amd64", "x86_64"},
+		"arm/7":    {"armv7", "arm", "armv6"},
+		"arm/6":    {"armv6", "arm"},
+		"arm/5":    {"armv5"},
+		"s390x/":   {"s390x"},
+		"ppc64le/": {"ppc64le"},
+	}
+	for platform, expected := range cases {
+		parts := strings.SplitN(platform, "/", 2)
+		got := lib.PlatformArchs(parts[0], parts[1])
+		if strings.Join(got, ",") == strings.Join(expected, ",") {
+			t.Logf("%s %v [expected]", platform, got)
+		} else {
+			t.Errorf("Unexpected %s %v [unexpected]", platform, got)
+		}
+	}
+}
+
+// TestArtifactURLAssets : the release assets give the artifact, signatures point to get.helm.sh
+func TestArtifactURLAssets(t *testing.T) {
+
+	os.Setenv("GOARM", "7")
+	defer os.Unsetenv("GOARM")
+
+	helm, _ := lib.LookupTool("helm")
+	release := modal.Repo{TagName: "v3.3.0"}
+	for _, name := range []string{"arm64", "arm", "amd64", "386", "ppc64le", "s390x"} {
+		release.Assets = append(release.Assets, modal.Assets{
+			Name:               "helm-v3.3.0-linux-" + name + ".tar.gz.asc",
+			BrowserDownloadURL: "https://github.com/helm/helm/releases/download/v3.3.0/helm-v3.3.0-linux-" + name + ".tar.gz.asc",
+		})
+	}
+	release.Assets = append(release.Assets, modal.Assets{
+		Name:               "helm-v3.3.0-windows-amd64.zip.asc",
+		BrowserDownloadURL: "https://github.com/helm/helm/releases/download/v3.3.0/helm-v3.3.0-windows-amd64.zip.asc",
+	})
+
+	cases := map[string]string{
+		"linux/arm":     "https://get.helm.sh/helm-v3.3.0-linux-arm.tar.gz",
+		"linux/arm64":   "https://get.helm.sh/helm-v3.3.0-linux-arm64.tar.gz",
+		"linux/386":     "https://get.helm.sh/helm-v3.3.0-linux-386.tar.gz",
+		"linux/s390x":   "https://get.helm.sh/helm-v3.3.0-linux-s390x.tar.gz",
+		"windows/amd64": "https://get.helm.sh/helm-v3.3.0-windows-amd64.zip",
+	}
+	for platform, expected := range cases {
+		parts := strings.Split(platform, "/")
+		url, err := helm.ArtifactURL(release, parts[0], parts[1])
+		if err == nil && url == expected {
+			t.Logf("%s %s [expected]", platform, url)
+		} else {
+			t.Errorf("Unexpected %s %q %v [unexpected]", platform, url, err)
+		}
+	}
+
+	if url, err := helm.ArtifactURL(release, "darwin", "arm64"); err != nil && strings.Contains(err.Error(), "no build for this platform") {
+		t.Logf("No build: %v [expected]", err)
+	} else {
+		t.Errorf("Unexpected %q %v [unexpected]", url, err)
+	}
+
+	/* an attached artifact is downloaded from GitHub */
+	kustomize, _ := lib.LookupTool("kustomize")
+	attached := modal.Repo{TagName: "kustomize/v3.8.1", Assets: []modal.Assets{
+		{Name: "checksums.txt", BrowserDownloadURL: "https://example.com/checksums.txt"},
+		{Name: "kustomize_v3.8.1_linux_amd64.tar.gz", BrowserDownloadURL: "https://example.com/kustomize_v3.8.1_linux_amd64.tar.gz"},
+	}}
+	if url, err := kustomize.ArtifactURL(attached, "linux", "amd64"); err == nil && url == "https://example.com/kustomize_v3.8.1_linux_amd64.tar.gz" {
+		t.Log("Attached artifact [expected]")
+	} else {
+		t.Errorf("Unexpected %q %v [unexpected]", url, err)
+	}
+}
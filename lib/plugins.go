@@ -0,0 +1,269 @@
+package lib
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Plugin states reported by PluginsStatus
+const (
+	PluginOK = "ok"
+	/* listed but not installed */
+	PluginMissing = "missing"
+	/* installed at another version than the listed one */
+	PluginDrift = "drift"
+	/* installed but listed nowhere, left alone */
+	PluginUnlisted = "unlisted"
+	/* listed for other helm versions only, left alone */
+	PluginNotApplicable = "n/a"
+)
+
+// Plugin : a helm plugin to install from url, at version when it is set, for the helm versions allowed by helm
+type Plugin struct {
+	Name    string `yaml:"name" json:"name"`
+	URL     string `yaml:"url" json:"url"`
+	Version string `yaml:"version" json:"version"`
+	Helm    string `yaml:"helm" json:"helm,omitempty"`
+}
+
+// Applies : whether the plugin is wanted with helm version, every version when it has no constraint
+func (p Plugin) Applies(version string) bool {
+	if p.Helm == "" {
+		return true
+	}
+	c, err := ParseConstraint(p.Helm)
+	return err == nil && c.Check(version)
+}
+
+// PluginState : a listed or installed plugin compared with the manifest
+type PluginState struct {
+	Name      string `json:"name"`
+	Wanted    string `json:"wanted"`
+	Helm      string `json:"helm,omitempty"`
+	Installed string `json:"installed"`
+	Status    string `json:"status"`
+}
+
+// MergePlugins : the plugins of the config, replaced or completed by those of the project
+//
+// A name may be listed once per helm constraint, eg. helm-diff 2.x for Helm 2 and 3.x for Helm 3;
+// the project replaces every entry of a name it lists.
+func MergePlugins(global []Plugin, project []Plugin) ([]Plugin, error) {
+	inProject := map[string]bool{}
+	for _, p := range project {
+		inProject[p.Name] = true
+	}
+
+	byKey := map[string]Plugin{}
+	for i, list := range [][]Plugin{global, project} {
+		for _, p := range list {
+			if p.Name == "" || p.URL == "" {
+				return nil, fmt.Errorf("plugin %q: name and url are required", p.Name)
+			}
+			if p.Helm != "" {
+				if _, err := ParseConstraint(p.Helm); err != nil {
+					return nil, fmt.Errorf("plugin %q: invalid helm %q: %v", p.Name, p.Helm, err)
+				}
+			}
+			if i == 0 && inProject[p.Name] {
+				continue
+			}
+			p.Version = strings.TrimPrefix(p.Version, "v")
+			byKey[p.Name+" "+p.Helm] = p
+		}
+	}
+
+	plugins := []Plugin{}
+	for _, p := range byKey {
+		plugins = append(plugins, p)
+	}
+	sort.Slice(plugins, func(i, j int) bool {
+		if plugins[i].Name != plugins[j].Name {
+			return plugins[i].Name < plugins[j].Name
+		}
+		return plugins[i].Helm < plugins[j].Helm
+	})
+	return plugins, nil
+}
+
+// PluginDir : where helm version keeps its plugins, with env overriding the environment
+func PluginDir(version string, env map[string]string) (string, error) {
+	home, helm2, err := helmHome(version, helmDataHome, env)
+	if err != nil {
+		return "", err
+	}
+	if !helm2 {
+		if plugins := envLookup(env)("HELM_PLUGINS"); plugins != "" {
+			return plugins, nil
+		}
+	}
+	return filepath.Join(home, "plugins"), nil
+}
+
+// InstalledPlugins : name and version of the plugins in dir, from their plugin.yaml
+func InstalledPlugins(dir string) (map[string]string, error) {
+	installed := map[string]string{}
+	files, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return installed, nil
+	}
+	if err != nil {
+		return installed, err
+	}
+
+	for _, f := range files {
+		/* helm installs plugins as symlinks to its cache */
+		path := filepath.Join(dir, f.Name(), "plugin.yaml")
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var metadata struct {
+			Name    string `yaml:"name"`
+			Version string `yaml:"version"`
+		}
+		if err := yaml.Unmarshal(content, &metadata); err != nil || metadata.Name == "" {
+			Report.Warn("ignoring invalid plugin %s", path)
+			continue
+		}
+		installed[metadata.Name] = strings.TrimPrefix(metadata.Version, "v")
+	}
+	return installed, nil
+}
+
+// PluginsStatus : the plugins listed for helm version compared with those installed in dir, then the unlisted ones
+//
+// Of the entries of a name, the first one allowing version is compared; a name listed for other versions
+// only is not applicable.
+func PluginsStatus(dir string, version string, plugins []Plugin) ([]PluginState, error) {
+	installed, err := InstalledPlugins(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	applicable := map[string]Plugin{}
+	others := map[string][]string{}
+	for _, p := range plugins {
+		if _, ok := applicable[p.Name]; ok {
+			continue
+		}
+		if p.Applies(version) {
+			applicable[p.Name] = p
+		} else {
+			others[p.Name] = append(others[p.Name], p.Helm)
+		}
+	}
+
+	states := []PluginState{}
+	listed := map[string]bool{}
+	for _, p := range plugins {
+		if listed[p.Name] {
+			continue
+		}
+		listed[p.Name] = true
+		a, ok := applicable[p.Name]
+		if !ok {
+			states = append(states, PluginState{Name: p.Name, Helm: strings.Join(others[p.Name], " || "), Installed: installed[p.Name], Status: PluginNotApplicable})
+			continue
+		}
+
+		state := PluginState{Name: a.Name, Wanted: a.Version, Helm: a.Helm, Status: PluginOK}
+		installedVersion, ok := installed[a.Name]
+		switch {
+		case !ok:
+			state.Status = PluginMissing
+		case a.Version != "" && installedVersion != a.Version:
+			state.Status = PluginDrift
+		}
+		state.Installed = installedVersion
+		states = append(states, state)
+	}
+
+	unlisted := []string{}
+	for name := range installed {
+		if !listed[name] {
+			unlisted = append(unlisted, name)
+		}
+	}
+	sort.Strings(unlisted)
+	for _, name := range unlisted {
+		states = append(states, PluginState{Name: name, Installed: installed[name], Status: PluginUnlisted})
+	}
+	return states, nil
+}
+
+// SyncPlugins : install the missing plugins and reinstall the drifted ones with the helm binary of version
+func SyncPlugins(binary string, version string, env map[string]string, plugins []Plugin) ([]PluginState, error) {
+	dir, err := PluginDir(version, env)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	states, err := PluginsStatus(dir, version, plugins)
+	if err != nil {
+		return nil, err
+	}
+
+	/* the entry of each name compared by PluginsStatus */
+	byName := map[string]Plugin{}
+	for _, p := range plugins {
+		if _, ok := byName[p.Name]; !ok && p.Applies(version) {
+			byName[p.Name] = p
+		}
+	}
+
+	failed := []string{}
+	for i, state := range states {
+		if state.Status != PluginMissing && state.Status != PluginDrift {
+			continue
+		}
+		p := byName[state.Name]
+
+		if state.Status == PluginDrift {
+			if err := runPlugin(binary, env, "remove", p.Name); err != nil {
+				failed = append(failed, fmt.Sprintf("%s: %v", p.Name, err))
+				continue
+			}
+		}
+		args := []string{"install", p.URL}
+		if p.Version != "" {
+			args = append(args, "--version", p.Version)
+		}
+		if err := runPlugin(binary, env, args...); err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", p.Name, err))
+			continue
+		}
+		Log.Infof("Installed plugin %s %s", p.Name, p.Version)
+		states[i].Status = PluginOK
+		states[i].Installed = p.Version
+	}
+
+	if len(failed) > 0 {
+		return states, fmt.Errorf("unable to sync plugins: %s", strings.Join(failed, "; "))
+	}
+	return states, nil
+}
+
+// runPlugin : run helm plugin with args, its output going to the log
+func runPlugin(binary string, env map[string]string, args ...string) error {
+	args = append([]string{"plugin"}, args...)
+	Log.Debugf("exec %s %s", binary, strings.Join(args, " "))
+
+	cmd := exec.Command(binary, args...)
+	cmd.Env = EnvList(os.Environ(), env)
+	output, err := cmd.CombinedOutput()
+	Log.Verbosef("%s", strings.TrimSpace(string(output)))
+	if err != nil {
+		return fmt.Errorf("%v: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
@@ -0,0 +1,181 @@
+package lib_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tokiwong/helm-switcher/lib"
+)
+
+/* a helm that installs plugins named after the last element of their url */
+const fakeHelmPlugin = `#!/bin/sh
+dir="$HELM_DATA_HOME/plugins"
+case "$2" in
+install)
+	name=$(basename "$3")
+	mkdir -p "$dir/$name"
+	printf 'name: %s\nversion: %s\n' "$name" "$5" > "$dir/$name/plugin.yaml" ;;
+remove)
+	rm -rf "$dir/$3" ;;
+esac
+`
+
+// TestMergePlugins : the project replaces the plugins of the config by name
+func TestMergePlugins(t *testing.T) {
+
+	global := []lib.Plugin{
+		{Name: "diff", URL: "https://github.com/databus23/helm-diff", Version: "v3.1.2"},
+		{Name: "secrets", URL: "https://github.com/jkroepke/helm-secrets", Version: "3.4.0"},
+	}
+	project := []lib.Plugin{{Name: "diff", URL: "https://github.com/databus23/helm-diff", Version: "3.1.3"}}
+
+	plugins, err := lib.MergePlugins(global, project)
+	if err == nil && len(plugins) == 2 && plugins[0].Name == "diff" && plugins[0].Version == "3.1.3" && plugins[1].Version == "3.4.0" {
+		t.Logf("Merged %v [expected]", plugins)
+	} else {
+		t.Errorf("Unexpected %v %v [unexpected]", plugins, err)
+	}
+
+	/* one entry per helm major, the project replaces both */
+	global = []lib.Plugin{
+		{Name: "diff", URL: "https://github.com/databus23/helm-diff", Version: "2.11.0+5", Helm: "< 3.0.0"},
+		{Name: "diff", URL: "https://github.com/databus23/helm-diff", Version: "3.1.3", Helm: ">= 3.0.0"},
+	}
+	plugins, err = lib.MergePlugins(global, nil)
+	if err == nil && len(plugins) == 2 && plugins[0].Helm == "< 3.0.0" && plugins[1].Helm == ">= 3.0.0" {
+		t.Logf("Entries per helm constraint kept %v [expected]", plugins)
+	} else {
+		t.Errorf("Unexpected %v %v [unexpected]", plugins, err)
+	}
+	plugins, err = lib.MergePlugins(global, project)
+	if err == nil && len(plugins) == 1 && plugins[0].Version == "3.1.3" && plugins[0].Helm == "" {
+		t.Logf("Project replaced every entry of diff %v [expected]", plugins)
+	} else {
+		t.Errorf("Unexpected %v %v [unexpected]", plugins, err)
+	}
+
+	if _, err := lib.MergePlugins([]lib.Plugin{{Name: "diff", URL: "https://github.com/databus23/helm-diff", Helm: "> three"}}, nil); err != nil {
+		t.Logf("Invalid helm constraint: %v [expected]", err)
+	} else {
+		t.Error("Invalid helm constraint accepted [unexpected]")
+	}
+
+	if _, err := lib.MergePlugins([]lib.Plugin{{Name: "diff"}}, nil); err != nil {
+		t.Logf("Plugin without url: %v [expected]", err)
+	} else {
+		t.Error("Plugin without url accepted [unexpected]")
+	}
+}
+
+// TestPluginDir : HELM_HOME for Helm 2, HELM_PLUGINS or HELM_DATA_HOME for Helm 3
+func TestPluginDir(t *testing.T) {
+
+	cases := []struct {
+		version string
+		env     map[string]string
+		dir     string
+	}{
+		{"2.16.9", map[string]string{"HELM_HOME": "/homes/v2"}, "/homes/v2/plugins"},
+		{"3.3.0", map[string]string{"HELM_DATA_HOME": "/homes/v3/data"}, "/homes/v3/data/plugins"},
+		{"3.3.0", map[string]string{"HELM_PLUGINS": "/plugins", "HELM_DATA_HOME": "/data"}, "/plugins"},
+		{"3.3.0", map[string]string{"HELM_PLUGINS": "", "HELM_DATA_HOME": "", "XDG_DATA_HOME": "/xdg"}, "/xdg/helm/plugins"},
+	}
+	for _, c := range cases {
+		if dir, err := lib.PluginDir(c.version, c.env); err == nil && dir == c.dir {
+			t.Logf("%s %s [expected]", c.version, dir)
+		} else {
+			t.Errorf("Unexpected %s %q %v [unexpected]", c.version, dir, err)
+		}
+	}
+}
+
+// TestSyncPlugins : missing plugins are installed, drifted ones reinstalled, unlisted ones left alone
+func TestSyncPlugins(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "helmswitch-plugins")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	binary := filepath.Join(dir, "helm_3.3.0")
+	ioutil.WriteFile(binary, []byte(fakeHelmPlugin), 0755)
+	env := map[string]string{"HELM_DATA_HOME": filepath.Join(dir, "data"), "HELM_PLUGINS": ""}
+	plugins := filepath.Join(dir, "data", "plugins")
+
+	for name, version := range map[string]string{"helm-diff": "3.1.2", "helm-unittest": "0.2.4"} {
+		os.MkdirAll(filepath.Join(plugins, name), 0755)
+		ioutil.WriteFile(filepath.Join(plugins, name, "plugin.yaml"), []byte("name: "+name+"\nversion: "+version+"\n"), 0644)
+	}
+
+	wanted := []lib.Plugin{
+		{Name: "helm-diff", URL: "https://github.com/databus23/helm-diff", Version: "3.1.3"},
+		{Name: "helm-secrets", URL: "https://github.com/jkroepke/helm-secrets", Version: "3.4.0"},
+	}
+
+	states, err := lib.PluginsStatus(plugins, "3.3.0", wanted)
+	expected := []string{lib.PluginDrift, lib.PluginMissing, lib.PluginUnlisted}
+	if err == nil && len(states) == 3 && states[0].Status == expected[0] && states[1].Status == expected[1] && states[2].Status == expected[2] {
+		t.Logf("Status %v [expected]", states)
+	} else {
+		t.Errorf("Unexpected status %v %v [unexpected]", states, err)
+	}
+
+	if _, err := lib.SyncPlugins(binary, "3.3.0", env, wanted); err != nil {
+		t.Fatal(err)
+	}
+	installed, _ := lib.InstalledPlugins(plugins)
+	if installed["helm-diff"] == "3.1.3" && installed["helm-secrets"] == "3.4.0" && installed["helm-unittest"] == "0.2.4" {
+		t.Logf("Synced %v [expected]", installed)
+	} else {
+		t.Errorf("Unexpected plugins %v [unexpected]", installed)
+	}
+}
+
+// TestSyncPlugins_Helm : only the entries allowing the helm version are synced, the others are not applicable
+func TestSyncPlugins_Helm(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "helmswitch-plugins")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	binary := filepath.Join(dir, "helm_3.3.0")
+	ioutil.WriteFile(binary, []byte(fakeHelmPlugin), 0755)
+	env := map[string]string{"HELM_DATA_HOME": filepath.Join(dir, "data"), "HELM_PLUGINS": ""}
+	plugins := filepath.Join(dir, "data", "plugins")
+
+	wanted := []lib.Plugin{
+		{Name: "helm-diff", URL: "https://github.com/databus23/helm-diff", Version: "2.11.0+5", Helm: "< 3.0.0"},
+		{Name: "helm-diff", URL: "https://github.com/databus23/helm-diff", Version: "3.1.3", Helm: ">= 3.0.0"},
+		{Name: "helm-tiller", URL: "https://github.com/rimusz/helm-tiller", Version: "0.9.3", Helm: "2.x"},
+	}
+
+	states, err := lib.SyncPlugins(binary, "3.3.0", env, wanted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	installed, _ := lib.InstalledPlugins(plugins)
+	if len(installed) == 1 && installed["helm-diff"] == "3.1.3" {
+		t.Logf("Synced the Helm 3 build only %v [expected]", installed)
+	} else {
+		t.Errorf("Unexpected plugins %v [unexpected]", installed)
+	}
+
+	if len(states) == 2 && states[0].Status == lib.PluginOK && states[0].Helm == ">= 3.0.0" && states[1].Status == lib.PluginNotApplicable {
+		t.Logf("Status %v [expected]", states)
+	} else {
+		t.Errorf("Unexpected status %v [unexpected]", states)
+	}
+
+	/* switching back to Helm 2 compares with the 2.x build */
+	states, err = lib.PluginsStatus(plugins, "2.16.9", wanted)
+	if err == nil && len(states) == 2 && states[0].Wanted == "2.11.0+5" && states[0].Status == lib.PluginDrift && states[1].Status == lib.PluginMissing {
+		t.Logf("Status with Helm 2 %v [expected]", states)
+	} else {
+		t.Errorf("Unexpected status with Helm 2 %v %v [unexpected]", states, err)
+	}
+}
@@ -0,0 +1,112 @@
+package lib
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+const policyFile = "policy.yaml"
+
+// What a policy rule does with the versions it matches
+const (
+	PolicyRefuse = "refuse"
+	PolicyWarn   = "warn"
+)
+
+// PolicyRule : versions of a tool, helm by default, that are banned or deprecated, and why
+type PolicyRule struct {
+	Tool     string `yaml:"tool" json:"tool"`
+	Versions string `yaml:"versions" json:"versions"`
+	Action   string `yaml:"action" json:"action"`
+	Reason   string `yaml:"reason" json:"reason"`
+
+	constraint *Constraint
+}
+
+// Policy : rules read from a policy file, eg. versions with a CVE or past their end of life
+type Policy struct {
+	Rules []PolicyRule `yaml:"rules" json:"rules"`
+}
+
+// PolicyPath : the policy file set in the config, policy.yaml in the config dir by default
+func PolicyPath(cfg *Config) string {
+	if cfg.Policy != "" {
+		return cfg.Policy
+	}
+	return filepath.Join(ConfigDir(), policyFile)
+}
+
+// LoadPolicy : read the policy file, a missing file gives an empty policy
+func LoadPolicy(path string) (*Policy, error) {
+	p := &Policy{}
+
+	content, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return p, nil
+	}
+	if err != nil {
+		return p, err
+	}
+
+	Log.Debugf("read %s", path)
+	if err := yaml.UnmarshalStrict(content, p); err != nil {
+		return &Policy{}, fmt.Errorf("%s: %v", path, err)
+	}
+
+	for i := range p.Rules {
+		rule := &p.Rules[i]
+		if rule.Tool == "" {
+			rule.Tool = "helm"
+		}
+		if rule.Action == "" {
+			rule.Action = PolicyRefuse
+		}
+		if rule.Action != PolicyRefuse && rule.Action != PolicyWarn {
+			return &Policy{}, fmt.Errorf("%s: rule %d: action must be %s or %s, not %q", path, i+1, PolicyRefuse, PolicyWarn, rule.Action)
+		}
+		if rule.constraint, err = ParseConstraint(rule.Versions); err != nil {
+			return &Policy{}, fmt.Errorf("%s: rule %d: %v", path, i+1, err)
+		}
+	}
+	return p, nil
+}
+
+// Evaluate : the rule matching version of the active tool, refusing rules before warning ones
+func (p *Policy) Evaluate(version string) (PolicyRule, bool) {
+	var match *PolicyRule
+	for i := range p.Rules {
+		rule := &p.Rules[i]
+		if rule.Tool != activeTool.Name || rule.constraint == nil || !rule.constraint.Check(version) {
+			continue
+		}
+		if match == nil || (match.Action == PolicyWarn && rule.Action == PolicyRefuse) {
+			match = rule
+		}
+	}
+	if match == nil {
+		return PolicyRule{}, false
+	}
+	return *match, true
+}
+
+// Enforce : warn about a deprecated version, refuse a banned one unless allowInsecure is set
+func (p *Policy) Enforce(version string, allowInsecure bool) error {
+	rule, ok := p.Evaluate(version)
+	if !ok {
+		return nil
+	}
+
+	if rule.Action == PolicyWarn {
+		Report.Warn("%s %s is deprecated by policy (%s): %s", rule.Tool, version, rule.Versions, rule.Reason)
+		return nil
+	}
+	if allowInsecure {
+		Report.Warn("%s %s is refused by policy (%s): %s, allowed by --allow-insecure", rule.Tool, version, rule.Versions, rule.Reason)
+		return nil
+	}
+	return fmt.Errorf("%s %s is refused by policy (%s): %s", rule.Tool, version, rule.Versions, rule.Reason)
+}
@@ -0,0 +1,77 @@
+package lib_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tokiwong/helm-switcher/lib"
+)
+
+const testPolicy = `rules:
+  - versions: "< 3.0.0"
+    reason: Helm 2 is end of life
+  - versions: ">= 3.2.0 < 3.2.4"
+    action: warn
+    reason: CVE-2020-4053
+  - versions: "3.2.0"
+    reason: CVE-2020-15185
+`
+
+// TestLoadPolicy : rules are read, refusing rules win over warnings
+func TestLoadPolicy(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "helmswitch-policy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "policy.yaml")
+
+	if p, err := lib.LoadPolicy(path); err == nil && len(p.Rules) == 0 {
+		t.Log("Missing policy is empty [expected]")
+	} else {
+		t.Errorf("Unexpected policy %v %v [unexpected]", p, err)
+	}
+
+	ioutil.WriteFile(path, []byte(testPolicy), 0644)
+	p, err := lib.LoadPolicy(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rule, ok := p.Evaluate("3.2.0"); ok && rule.Reason == "CVE-2020-15185" {
+		t.Log("Refusing rule wins [expected]")
+	} else {
+		t.Errorf("Unexpected rule %+v [unexpected]", rule)
+	}
+
+	if err := p.Enforce("2.16.1", false); err != nil {
+		t.Logf("Banned version refused: %v [expected]", err)
+	} else {
+		t.Error("Banned version allowed [unexpected]")
+	}
+	if err := p.Enforce("2.16.1", true); err == nil {
+		t.Log("Banned version allowed with allowInsecure [expected]")
+	} else {
+		t.Errorf("Unexpected error %v [unexpected]", err)
+	}
+	if err := p.Enforce("3.2.1", false); err == nil {
+		t.Log("Deprecated version only warns [expected]")
+	} else {
+		t.Errorf("Unexpected error %v [unexpected]", err)
+	}
+	if _, ok := p.Evaluate("3.3.0"); !ok {
+		t.Log("Allowed version matches no rule [expected]")
+	} else {
+		t.Error("Allowed version matched a rule [unexpected]")
+	}
+
+	ioutil.WriteFile(path, []byte("rules:\n  - versions: \"< 3\"\n    action: block\n"), 0644)
+	if _, err := lib.LoadPolicy(path); err != nil {
+		t.Logf("Unknown action refused: %v [expected]", err)
+	} else {
+		t.Error("Unknown action accepted [unexpected]")
+	}
+}
@@ -0,0 +1,46 @@
+package lib
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// projectFile : settings of a project, found in its dir or the nearest parent
+const projectFile = ".helmswitch.yaml"
+
+// ProjectConfig : what a project expects of helm, read from .helmswitch.yaml
+type ProjectConfig struct {
+	/* the helm version the project is used with */
+	Version      string       `yaml:"version"`
+	Plugins      []Plugin     `yaml:"plugins"`
+	Repositories []Repository `yaml:"repositories"`
+}
+
+// FindProjectConfig : the nearest .helmswitch.yaml from dir up to the root, with its path, empty when there is none
+func FindProjectConfig(dir string) (*ProjectConfig, string, error) {
+	for {
+		path := filepath.Join(dir, projectFile)
+		if content, err := ioutil.ReadFile(path); err == nil {
+			Log.Debugf("read %s", path)
+			project := &ProjectConfig{}
+			if err := yaml.UnmarshalStrict(content, project); err != nil {
+				return &ProjectConfig{}, path, fmt.Errorf("%s: %v", path, err)
+			}
+			project.Version = strings.TrimPrefix(project.Version, "v")
+			if project.Version != "" && !ValidVersionFormat(project.Version) {
+				return &ProjectConfig{}, path, fmt.Errorf("%s: invalid version %q", path, project.Version)
+			}
+			return project, path, nil
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return &ProjectConfig{}, "", nil
+		}
+		dir = parent
+	}
+}
@@ -0,0 +1,38 @@
+package lib_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tokiwong/helm-switcher/lib"
+)
+
+// TestFindProjectConfig : the nearest .helmswitch.yaml above the dir is used
+func TestFindProjectConfig(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "helmswitch-project")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	nested := filepath.Join(dir, "charts", "app")
+	os.MkdirAll(nested, 0755)
+	ioutil.WriteFile(filepath.Join(dir, ".helmswitch.yaml"), []byte("plugins:\n  - name: diff\n    url: https://github.com/databus23/helm-diff\n    version: 3.1.3\n"), 0644)
+
+	project, path, err := lib.FindProjectConfig(nested)
+	if err == nil && path == filepath.Join(dir, ".helmswitch.yaml") && len(project.Plugins) == 1 && project.Plugins[0].Version == "3.1.3" {
+		t.Logf("Found %s [expected]", path)
+	} else {
+		t.Errorf("Unexpected %v %q %v [unexpected]", project, path, err)
+	}
+
+	ioutil.WriteFile(filepath.Join(dir, ".helmswitch.yaml"), []byte("plugin: []\n"), 0644)
+	if _, _, err := lib.FindProjectConfig(nested); err != nil {
+		t.Logf("Unknown key: %v [expected]", err)
+	} else {
+		t.Error("Unknown key accepted [unexpected]")
+	}
+}
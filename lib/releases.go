@@ -0,0 +1,147 @@
+package lib
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/tokiwong/helm-switcher/modal"
+)
+
+const (
+	releaseIndexFile = "releases.json"
+
+	/* how long the cached releases are used before asking GitHub again */
+	releaseIndexTTL = time.Hour
+)
+
+// ReleaseIndex : releases fetched from GitHub, cached in releases.json in the install dir
+type ReleaseIndex struct {
+	FetchedAt time.Time    `json:"fetched_at"`
+	Releases  []modal.Repo `json:"releases"`
+}
+
+// NewReleaseIndex : index of releases fetched now
+func NewReleaseIndex(releases []modal.Repo) *ReleaseIndex {
+	return &ReleaseIndex{FetchedAt: time.Now().UTC(), Releases: releases}
+}
+
+// LoadReleaseIndex : read the cached releases from dir
+func LoadReleaseIndex(dir string) (*ReleaseIndex, error) {
+	content, err := ioutil.ReadFile(filepath.Join(dir, releaseIndexFile))
+	if err != nil {
+		return nil, err
+	}
+
+	index := &ReleaseIndex{}
+	if err := json.Unmarshal(content, index); err != nil {
+		return nil, fmt.Errorf("corrupt %s: %v", releaseIndexFile, err)
+	}
+	Log.Debugf("read %s, fetched %s", filepath.Join(dir, releaseIndexFile), index.FetchedAt)
+	return index, nil
+}
+
+// Save : write releases.json atomically
+func (idx *ReleaseIndex) Save(dir string) error {
+	content, err := json.Marshal(idx)
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(dir, releaseIndexFile)
+	Log.Debugf("write %s", path)
+	if err := ioutil.WriteFile(path+".tmp", content, 0644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// Fresh : whether the index is recent enough to be used without asking GitHub
+func (idx *ReleaseIndex) Fresh(now time.Time) bool {
+	return now.Sub(idx.FetchedAt) < releaseIndexTTL
+}
+
+// Versions : released versions, newest first
+func (idx *ReleaseIndex) Versions() []string {
+	versions := []string{}
+	for _, r := range idx.sorted() {
+		versions = append(versions, ReleaseVersion(r))
+	}
+	return versions
+}
+
+// Find : the release of version
+func (idx *ReleaseIndex) Find(version string) (modal.Repo, bool) {
+	for _, r := range idx.Releases {
+		if ReleaseVersion(r) == version {
+			return r, true
+		}
+	}
+	return modal.Repo{}, false
+}
+
+// Between : releases after from up to and including to, newest first
+func (idx *ReleaseIndex) Between(from string, to string) ([]modal.Repo, error) {
+	fromVersion, err := NewVersion(from)
+	if err != nil {
+		return nil, err
+	}
+	toVersion, err := NewVersion(to)
+	if err != nil {
+		return nil, err
+	}
+	if toVersion.LessThan(*fromVersion) {
+		return nil, fmt.Errorf("%s is older than %s", to, from)
+	}
+
+	releases := []modal.Repo{}
+	for _, r := range idx.sorted() {
+		sv, _ := NewVersion(ReleaseVersion(r))
+		if fromVersion.LessThan(*sv) && !toVersion.LessThan(*sv) {
+			releases = append(releases, r)
+		}
+	}
+	return releases, nil
+}
+
+// sorted : releases with a valid version, newest first
+func (idx *ReleaseIndex) sorted() []modal.Repo {
+	byVersion := map[string]modal.Repo{}
+	semvers := []*Version{}
+	for _, r := range idx.Releases {
+		sv, err := NewVersion(ReleaseVersion(r))
+		if err != nil {
+			continue
+		}
+		if _, ok := byVersion[sv.String()]; !ok {
+			semvers = append(semvers, sv)
+		}
+		byVersion[sv.String()] = r
+	}
+	Sort(semvers)
+
+	releases := []modal.Repo{}
+	for _, sv := range semvers {
+		releases = append(releases, byVersion[sv.String()])
+	}
+	return releases
+}
+
+// ParseVersionRange : a single version, or from..to
+func ParseVersionRange(arg string) (string, string, error) {
+	parts := strings.SplitN(arg, "..", 2)
+	for i := range parts {
+		parts[i] = strings.TrimPrefix(strings.TrimSpace(parts[i]), "v")
+		if !ValidVersionFormat(parts[i]) {
+			return "", "", fmt.Errorf("invalid version %q in %q, expecting 3.3.0 or 3.1.0..3.3.0", parts[i], arg)
+		}
+	}
+	if len(parts) == 1 {
+		return "", parts[0], nil
+	}
+	return parts[0], parts[1], nil
+}
@@ -0,0 +1,97 @@
+package lib_test
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tokiwong/helm-switcher/lib"
+	"github.com/tokiwong/helm-switcher/modal"
+)
+
+func testReleaseIndex() *lib.ReleaseIndex {
+	return lib.NewReleaseIndex([]modal.Repo{
+		{TagName: "v3.1.0", Body: "first"},
+		{TagName: "v3.3.0", Body: "third"},
+		{TagName: "v3.2.4", Body: "second"},
+		{TagName: "v2.16.1", Body: "old"},
+	})
+}
+
+// TestReleaseIndexSave : the index survives a round trip and expires
+func TestReleaseIndexSave(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "helmswitch-releases")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := testReleaseIndex().Save(dir); err != nil {
+		t.Fatal(err)
+	}
+	index, err := lib.LoadReleaseIndex(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Join(index.Versions(), " ") == "3.3.0 3.2.4 3.1.0 2.16.1" {
+		t.Log("Cached versions sorted newest first [expected]")
+	} else {
+		t.Errorf("Unexpected versions %v [unexpected]", index.Versions())
+	}
+
+	if index.Fresh(time.Now()) && !index.Fresh(time.Now().Add(2*time.Hour)) {
+		t.Log("Index expires [expected]")
+	} else {
+		t.Error("Index freshness is wrong [unexpected]")
+	}
+}
+
+// TestReleaseIndexBetween : releases after from up to and including to
+func TestReleaseIndexBetween(t *testing.T) {
+
+	releases, err := testReleaseIndex().Between("3.1.0", "3.3.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bodies := []string{}
+	for _, r := range releases {
+		bodies = append(bodies, r.Body)
+	}
+	if strings.Join(bodies, " ") == "third second" {
+		t.Log("Range excludes from and includes to [expected]")
+	} else {
+		t.Errorf("Unexpected releases %v [unexpected]", bodies)
+	}
+
+	if _, err := testReleaseIndex().Between("3.3.0", "3.1.0"); err != nil {
+		t.Logf("Reversed range refused: %v [expected]", err)
+	} else {
+		t.Error("Reversed range accepted [unexpected]")
+	}
+}
+
+// TestParseVersionRange : a single version or from..to
+func TestParseVersionRange(t *testing.T) {
+
+	if from, to, err := lib.ParseVersionRange("v3.3.0"); err == nil && from == "" && to == "3.3.0" {
+		t.Log("Single version [expected]")
+	} else {
+		t.Errorf("Unexpected %q %q %v [unexpected]", from, to, err)
+	}
+
+	if from, to, err := lib.ParseVersionRange("3.1.0..3.3.0"); err == nil && from == "3.1.0" && to == "3.3.0" {
+		t.Log("Version range [expected]")
+	} else {
+		t.Errorf("Unexpected %q %q %v [unexpected]", from, to, err)
+	}
+
+	if _, _, err := lib.ParseVersionRange("3.1..3.3.0"); err != nil {
+		t.Logf("Invalid range refused: %v [expected]", err)
+	} else {
+		t.Error("Invalid range accepted [unexpected]")
+	}
+}
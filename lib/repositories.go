@@ -0,0 +1,170 @@
+package lib
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// What SetupRepositories did with each repository
+const (
+	RepositoryAdded     = "added"
+	RepositoryUpdated   = "updated"
+	RepositoryUnchanged = "unchanged"
+)
+
+// Repository : a chart repository a project uses
+type Repository struct {
+	Name string `yaml:"name" json:"name"`
+	URL  string `yaml:"url" json:"url"`
+}
+
+// RepositoryState : what setup did with a repository
+type RepositoryState struct {
+	Name   string `json:"name"`
+	URL    string `json:"url"`
+	Status string `json:"status"`
+}
+
+/* repositories.yaml of helm, entries kept as read so the fields helmswitch does not know survive */
+type repositoryFile struct {
+	APIVersion   string          `yaml:"apiVersion"`
+	Generated    time.Time       `yaml:"generated"`
+	Repositories []yaml.MapSlice `yaml:"repositories"`
+}
+
+// RepositoryFile : the repositories.yaml of helm version, with env overriding the environment
+func RepositoryFile(version string, env map[string]string) (string, error) {
+	home, helm2, err := helmHome(version, helmConfigHome, env)
+	if err != nil {
+		return "", err
+	}
+	if helm2 {
+		return filepath.Join(home, "repository", "repositories.yaml"), nil
+	}
+	if config := envLookup(env)("HELM_REPOSITORY_CONFIG"); config != "" {
+		return config, nil
+	}
+	return filepath.Join(home, "repositories.yaml"), nil
+}
+
+// SetupRepositories : add the repositories to the repositories.yaml at path, in the format of helm version,
+// without fetching their index
+func SetupRepositories(path string, version string, repositories []Repository) ([]RepositoryState, error) {
+	sv, err := NewVersion(version)
+	if err != nil {
+		return nil, err
+	}
+	helm2 := sv.Major < 3
+
+	file := &repositoryFile{}
+	content, err := ioutil.ReadFile(path)
+	switch {
+	case err == nil:
+		Log.Debugf("read %s", path)
+		if err := yaml.Unmarshal(content, file); err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+	case !os.IsNotExist(err):
+		return nil, err
+	}
+	if helm2 || file.APIVersion == "" {
+		file.APIVersion = "v1"
+	}
+
+	/* helm 2 keeps the indexes next to repositories.yaml, helm 3 in its cache */
+	cacheDir := filepath.Join(filepath.Dir(path), "cache")
+
+	states := []RepositoryState{}
+	for _, r := range repositories {
+		if r.Name == "" || r.URL == "" {
+			return nil, fmt.Errorf("repository %q: name and url are required", r.Name)
+		}
+		state := RepositoryState{Name: r.Name, URL: r.URL, Status: RepositoryAdded}
+
+		index := -1
+		for i, entry := range file.Repositories {
+			if name, _ := mapValue(entry, "name").(string); name == r.Name {
+				index = i
+			}
+		}
+		if index < 0 {
+			entry := yaml.MapSlice{}
+			for _, key := range []string{"caFile", "certFile", "keyFile", "name", "password", "url", "username"} {
+				entry = append(entry, yaml.MapItem{Key: key, Value: ""})
+			}
+			if !helm2 {
+				entry = append(entry, yaml.MapItem{Key: "insecure_skip_tls_verify", Value: false})
+			}
+			file.Repositories = append(file.Repositories, entry)
+			index = len(file.Repositories) - 1
+		} else if url, _ := mapValue(file.Repositories[index], "url").(string); url == r.URL {
+			state.Status = RepositoryUnchanged
+		} else {
+			state.Status = RepositoryUpdated
+		}
+
+		entry := setMapValue(file.Repositories[index], "name", r.Name)
+		entry = setMapValue(entry, "url", r.URL)
+		if helm2 {
+			entry = setMapValue(entry, "cache", filepath.Join(cacheDir, r.Name+"-index.yaml"))
+		}
+		file.Repositories[index] = entry
+		states = append(states, state)
+	}
+
+	file.Generated = time.Now().UTC()
+	content, err = yaml.Marshal(file)
+	if err != nil {
+		return nil, err
+	}
+	mkdirs := []string{filepath.Dir(path)}
+	if helm2 {
+		mkdirs = append(mkdirs, cacheDir)
+	}
+	for _, dir := range mkdirs {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, err
+		}
+	}
+
+	/* the file holds the credentials of the repositories, keep its mode, only the owner reads a new one */
+	mode := os.FileMode(0600)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	Log.Debugf("write %s", path)
+	if err := ioutil.WriteFile(path+".tmp", content, mode); err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path+".tmp", mode); err != nil {
+		return nil, err
+	}
+	return states, os.Rename(path+".tmp", path)
+}
+
+// mapValue : the value of key in entry, nil when it is not set
+func mapValue(entry yaml.MapSlice, key string) interface{} {
+	for _, item := range entry {
+		if item.Key == key {
+			return item.Value
+		}
+	}
+	return nil
+}
+
+// setMapValue : entry with key set to value, appended when it is not set yet
+func setMapValue(entry yaml.MapSlice, key string, value interface{}) yaml.MapSlice {
+	for i, item := range entry {
+		if item.Key == key {
+			entry[i].Value = value
+			return entry
+		}
+	}
+	return append(entry, yaml.MapItem{Key: key, Value: value})
+}
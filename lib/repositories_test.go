@@ -0,0 +1,120 @@
+package lib_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tokiwong/helm-switcher/lib"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// TestRepositoryFile : repository/repositories.yaml in HELM_HOME for Helm 2, HELM_CONFIG_HOME for Helm 3
+func TestRepositoryFile(t *testing.T) {
+
+	cases := []struct {
+		version string
+		env     map[string]string
+		file    string
+	}{
+		{"2.16.9", map[string]string{"HELM_HOME": "/homes/v2"}, "/homes/v2/repository/repositories.yaml"},
+		{"3.3.0", map[string]string{"HELM_REPOSITORY_CONFIG": "", "HELM_CONFIG_HOME": "/homes/v3/config"}, "/homes/v3/config/repositories.yaml"},
+		{"3.3.0", map[string]string{"HELM_REPOSITORY_CONFIG": "/repos.yaml"}, "/repos.yaml"},
+	}
+	for _, c := range cases {
+		if file, err := lib.RepositoryFile(c.version, c.env); err == nil && file == c.file {
+			t.Logf("%s %s [expected]", c.version, file)
+		} else {
+			t.Errorf("Unexpected %s %q %v [unexpected]", c.version, file, err)
+		}
+	}
+}
+
+// TestSetupRepositories : repositories are added or updated by name, the others and their fields kept
+func TestSetupRepositories(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "helmswitch-repositories")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	existing := `apiVersion: ""
+generated: "2020-08-01T10:00:00Z"
+repositories:
+- caFile: ""
+  certFile: ""
+  insecure_skip_tls_verify: false
+  keyFile: ""
+  name: private
+  pass_credentials_all: true
+  password: secret
+  url: https://charts.example.com
+  username: me
+- name: bitnami
+  url: https://charts.bitnami.com
+`
+	path := filepath.Join(dir, "config", "repositories.yaml")
+	os.MkdirAll(filepath.Dir(path), 0755)
+	ioutil.WriteFile(path, []byte(existing), 0644)
+	os.Chmod(path, 0640)
+
+	repositories := []lib.Repository{
+		{Name: "stable", URL: "https://charts.helm.sh/stable"},
+		{Name: "bitnami", URL: "https://charts.bitnami.com/bitnami"},
+		{Name: "private", URL: "https://charts.example.com"},
+	}
+	states, err := lib.SetupRepositories(path, "3.3.0", repositories)
+	if err == nil && len(states) == 3 && states[0].Status == lib.RepositoryAdded && states[1].Status == lib.RepositoryUpdated && states[2].Status == lib.RepositoryUnchanged {
+		t.Logf("States %v [expected]", states)
+	} else {
+		t.Errorf("Unexpected states %v %v [unexpected]", states, err)
+	}
+
+	content, _ := ioutil.ReadFile(path)
+	var file struct {
+		APIVersion   string                   `yaml:"apiVersion"`
+		Repositories []map[string]interface{} `yaml:"repositories"`
+	}
+	if err := yaml.Unmarshal(content, &file); err != nil {
+		t.Fatal(err)
+	}
+	if len(file.Repositories) == 3 && file.Repositories[0]["password"] == "secret" && file.Repositories[0]["pass_credentials_all"] == true &&
+		file.Repositories[1]["url"] == "https://charts.bitnami.com/bitnami" && file.Repositories[2]["name"] == "stable" {
+		t.Log("Repositories merged [expected]")
+	} else {
+		t.Errorf("Unexpected repositories.yaml:\n%s [unexpected]", content)
+	}
+	if info, err := os.Stat(path); err == nil && info.Mode().Perm() == 0640 {
+		t.Log("Mode of repositories.yaml kept [expected]")
+	} else {
+		t.Errorf("Mode of repositories.yaml changed: %v %v [unexpected]", info, err)
+	}
+
+	/* helm 2 entries point at the index in the cache dir */
+	path2 := filepath.Join(dir, "helm2", "repository", "repositories.yaml")
+	if _, err := lib.SetupRepositories(path2, "2.16.9", repositories[:1]); err != nil {
+		t.Fatal(err)
+	}
+	content, _ = ioutil.ReadFile(path2)
+	cache := filepath.Join(dir, "helm2", "repository", "cache")
+	if strings.Contains(string(content), "apiVersion: v1") && strings.Contains(string(content), "cache: "+filepath.Join(cache, "stable-index.yaml")) {
+		t.Log("Helm 2 format [expected]")
+	} else {
+		t.Errorf("Unexpected Helm 2 repositories.yaml:\n%s [unexpected]", content)
+	}
+	if info, err := os.Stat(cache); err == nil && info.IsDir() {
+		t.Log("Helm 2 cache dir created [expected]")
+	} else {
+		t.Errorf("No cache dir: %v [unexpected]", err)
+	}
+
+	/* a new file holds credentials too, only the owner reads it */
+	if info, err := os.Stat(path2); err == nil && info.Mode().Perm() == 0600 {
+		t.Log("New repositories.yaml is 0600 [expected]")
+	} else {
+		t.Errorf("New repositories.yaml is not 0600: %v %v [unexpected]", info, err)
+	}
+}
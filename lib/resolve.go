@@ -0,0 +1,73 @@
+package lib
+
+import "strings"
+
+// Where a resolved version comes from, the most explicit first
+const (
+	ResolvedArgument = "argument"
+	/* .helm-version or the helm line of .tool-versions */
+	ResolvedPin = "pin"
+	/* version of the nearest .helmswitch.yaml */
+	ResolvedProject = "project"
+	/* the Chart.yaml files of the tree */
+	ResolvedCharts = "charts"
+	ResolvedActive = "active"
+)
+
+// Resolution : the version a command uses, where it comes from and why
+type Resolution struct {
+	Version string `json:"version"`
+	From    string `json:"from"`
+	/* the pin or project file, the chart or the bin path the version was read from */
+	Source string `json:"source"`
+	/* what the charts of the tree require, also when no installed version satisfies it */
+	Reason string          `json:"reason,omitempty"`
+	Charts *ChartInference `json:"charts,omitempty"`
+}
+
+// Pinned : whether a file of the project sets the version
+func (r Resolution) Pinned() bool {
+	return r.From == ResolvedPin || r.From == ResolvedProject
+}
+
+// ResolveVersion : the version given as argument, else the one pinned in dir or its parents, the one of the
+// nearest .helmswitch.yaml, the one the charts of the tree require, or the one active at binPath;
+// the version is empty when none of them decides
+func ResolveVersion(dir string, argument string, binPath string, storeDir string) Resolution {
+	if argument != "" {
+		return Resolution{Version: strings.TrimPrefix(argument, "v"), From: ResolvedArgument, Source: ResolvedArgument}
+	}
+	if pinned, file := FindPinnedVersion(dir); pinned != "" {
+		return Resolution{Version: pinned, From: ResolvedPin, Source: file}
+	}
+
+	r := Resolution{}
+	active := ActiveVersion(binPath, storeDir)
+
+	/* the project file and the charts are about helm */
+	if activeTool.Name == "helm" {
+		project, file, err := FindProjectConfig(dir)
+		if err != nil {
+			Report.Warn("%v", err)
+		} else if project.Version != "" {
+			return Resolution{Version: project.Version, From: ResolvedProject, Source: file}
+		}
+
+		if inference, ok := InferFromCharts(dir); ok {
+			r.Charts, r.Reason = &inference, inference.Reason
+			if inference.Constraint != nil {
+				if resolved, ok := inference.Resolve(active, ListInstalledVersions(storeDir)); ok {
+					r.Version, r.From, r.Source = resolved, ResolvedCharts, inference.Charts[0].Path
+				} else {
+					r.Reason = inference.Reason + ", and none is installed"
+				}
+				return r
+			}
+		}
+	}
+
+	if active != "" {
+		r.Version, r.From, r.Source = active, ResolvedActive, binPath
+	}
+	return r
+}
@@ -0,0 +1,80 @@
+package lib_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tokiwong/helm-switcher/lib"
+)
+
+// TestResolveVersion : argument, pin, project, charts and active version, in that order
+func TestResolveVersion(t *testing.T) {
+
+	root, err := ioutil.TempDir("", "helmswitch-resolve")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	store := filepath.Join(root, "store") + "/"
+	os.MkdirAll(store, 0755)
+	ioutil.WriteFile(store+"helm_2.16.9", []byte("helm 2"), 0755)
+	ioutil.WriteFile(store+"helm_3.3.0", []byte("helm 3"), 0755)
+	binPath := filepath.Join(root, "helm")
+	os.Symlink(store+"helm_2.16.9", binPath)
+
+	project := filepath.Join(root, "project")
+	charts := filepath.Join(root, "charts")
+	empty := filepath.Join(root, "empty")
+	for _, dir := range []string{project, charts, empty} {
+		os.MkdirAll(dir, 0755)
+	}
+	ioutil.WriteFile(filepath.Join(project, ".helmswitch.yaml"), []byte("version: 3.2.4\n"), 0644)
+	ioutil.WriteFile(filepath.Join(charts, "Chart.yaml"), []byte("apiVersion: v2\nname: app\nversion: 0.1.0\n"), 0644)
+
+	cases := []struct {
+		name     string
+		dir      string
+		argument string
+		version  string
+		from     string
+	}{
+		{"argument", project, "v3.1.0", "3.1.0", lib.ResolvedArgument},
+		{"project", project, "", "3.2.4", lib.ResolvedProject},
+		{"charts", charts, "", "3.3.0", lib.ResolvedCharts},
+		{"active", empty, "", "2.16.9", lib.ResolvedActive},
+	}
+	for _, c := range cases {
+		r := lib.ResolveVersion(c.dir, c.argument, binPath, store)
+		if r.Version == c.version && r.From == c.from {
+			t.Logf("%s: %s from %s [expected]", c.name, r.Version, r.Source)
+		} else {
+			t.Errorf("%s: unexpected %+v [unexpected]", c.name, r)
+		}
+	}
+
+	if r := lib.ResolveVersion(project, "", binPath, store); r.Pinned() && r.Source == filepath.Join(project, ".helmswitch.yaml") {
+		t.Log("Project version counts as pinned [expected]")
+	} else {
+		t.Errorf("Unexpected %+v [unexpected]", r)
+	}
+
+	/* a pin wins over the project */
+	ioutil.WriteFile(filepath.Join(project, ".helm-version"), []byte("3.0.0\n"), 0644)
+	if r := lib.ResolveVersion(project, "", binPath, store); r.Version == "3.0.0" && r.From == lib.ResolvedPin {
+		t.Logf("Pin %s wins [expected]", r.Version)
+	} else {
+		t.Errorf("Unexpected %+v [unexpected]", r)
+	}
+
+	/* charts requiring Helm 3 with none installed decide nothing, and say why */
+	os.Remove(store + "helm_3.3.0")
+	os.Remove(store + "state.json")
+	if r := lib.ResolveVersion(charts, "", binPath, store); r.Version == "" && r.Reason != "" && r.Charts != nil {
+		t.Logf("No version: %s [expected]", r.Reason)
+	} else {
+		t.Errorf("Unexpected %+v [unexpected]", r)
+	}
+}
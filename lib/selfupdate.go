@@ -0,0 +1,198 @@
+package lib
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/tokiwong/helm-switcher/modal"
+)
+
+const (
+	selfReleaseURL    = "https://api.github.com/repos/tokiwong/helm-switcher/releases/latest"
+	selfChecksumsFile = "checksums.txt"
+)
+
+// BuildInfo : what helmswitch was built from, set with -ldflags at release time
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	Date      string `json:"date"`
+	GoVersion string `json:"go_version"`
+	Platform  string `json:"platform"`
+}
+
+// NewBuildInfo : build info of the running binary
+func NewBuildInfo(version string, commit string, date string) BuildInfo {
+	return BuildInfo{
+		Version:   version,
+		Commit:    commit,
+		Date:      date,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+	}
+}
+
+// String : one line description, as printed by --version
+func (b BuildInfo) String() string {
+	return fmt.Sprintf("%s (commit %s, built %s, %s, %s)", b.Version, b.Commit, b.Date, b.GoVersion, b.Platform)
+}
+
+// SelfAssetName : name of the release asset for a platform, eg. helmswitch_linux_amd64
+func SelfAssetName(goos string, goarch string) string {
+	return "helmswitch_" + goos + "_" + goarch
+}
+
+// LatestSelfRelease : the latest release of helmswitch itself
+func LatestSelfRelease(releaseURL string) (modal.Repo, error) {
+	if releaseURL == "" {
+		releaseURL = selfReleaseURL
+	}
+	var release modal.Repo
+
+	req, err := http.NewRequest(http.MethodGet, releaseURL, nil)
+	if err != nil {
+		return release, err
+	}
+	req.Header.Set("User-Agent", "helmswitch")
+
+	res, err := NewHTTPClient(10 * time.Second).Do(req)
+	if err != nil {
+		return release, err
+	}
+	defer res.Body.Close()
+	LogResponse(res)
+
+	if res.StatusCode != http.StatusOK {
+		printRateLimit(res.Header)
+		return release, fmt.Errorf("unable to get the latest helmswitch release: %s", res.Status)
+	}
+	err = json.NewDecoder(res.Body).Decode(&release)
+	return release, err
+}
+
+// SelfUpdateAvailable : whether release is newer than the running version, always true for development builds
+func SelfUpdateAvailable(current string, release modal.Repo) bool {
+	cv, err := NewVersion(strings.TrimPrefix(current, "v"))
+	if err != nil {
+		return true
+	}
+	rv, err := NewVersion(strings.TrimPrefix(release.TagName, "v"))
+	if err != nil {
+		return false
+	}
+	return cv.LessThan(*rv)
+}
+
+// SelfUpdate : download the release binary for this platform, verify it against the release checksums and
+// replace executable with it atomically
+func SelfUpdate(release modal.Repo, executable string) (string, error) {
+	name := SelfAssetName(runtime.GOOS, runtime.GOARCH)
+
+	var binaryURL, checksumsURL, sumURL string
+	for _, asset := range release.Assets {
+		switch asset.Name {
+		case name:
+			binaryURL = asset.BrowserDownloadURL
+		case selfChecksumsFile:
+			checksumsURL = asset.BrowserDownloadURL
+		case name + ".sha256":
+			sumURL = asset.BrowserDownloadURL
+		}
+	}
+	if binaryURL == "" {
+		return "", fmt.Errorf("release %s has no build for %s/%s (expecting an asset named %s)", release.TagName, runtime.GOOS, runtime.GOARCH, name)
+	}
+	if checksumsURL == "" && sumURL == "" {
+		return "", fmt.Errorf("release %s publishes no checksum for %s, refusing to install it", release.TagName, name)
+	}
+
+	expected, err := selfChecksum(checksumsURL, sumURL, name)
+	if err != nil {
+		return "", err
+	}
+
+	/* replace the file behind the symlink, renaming within its directory keeps the swap atomic */
+	target, err := filepath.EvalSymlinks(executable)
+	if err != nil {
+		return "", err
+	}
+	info, err := os.Stat(target)
+	if err != nil {
+		return "", err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(target), ".helmswitch-update-")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+
+	Log.Infof("Downloading %s", binaryURL)
+	if err := httpGet(binaryURL, tmp); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+
+	sum, err := FileChecksum(tmp.Name())
+	if err != nil {
+		return "", err
+	}
+	Log.Verbosef("sha256 %s", sum)
+	if sum != expected {
+		return "", fmt.Errorf("checksum mismatch for %s: expected %s, downloaded %s", name, expected, sum)
+	}
+
+	if err := os.Chmod(tmp.Name(), info.Mode().Perm()|0111); err != nil {
+		return "", err
+	}
+	Log.Debugf("rename %s -> %s", tmp.Name(), target)
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		return "", err
+	}
+	return sum, nil
+}
+
+// selfChecksum : the published sha256 of name, from checksums.txt or from name.sha256
+func selfChecksum(checksumsURL string, sumURL string, name string) (string, error) {
+	var buffer strings.Builder
+	url := checksumsURL
+	if url == "" {
+		url = sumURL
+	}
+	if err := httpGet(url, &buffer); err != nil {
+		return "", err
+	}
+
+	if url == sumURL {
+		return ParseChecksum(buffer.String(), name, ChecksumSHA256)
+	}
+	return ParseChecksum(buffer.String(), name, ChecksumSHA256Sums)
+}
+
+// httpGet : copy the body of url to w, failing on any status but 200
+func httpGet(url string, w io.Writer) error {
+	res, err := NewHTTPClient(0).Get(url)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	LogResponse(res)
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("unable to download %s: %s", url, res.Status)
+	}
+	n, err := io.Copy(w, res.Body)
+	Log.Verbosef("%d bytes downloaded.", n)
+	return err
+}
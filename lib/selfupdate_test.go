@@ -0,0 +1,87 @@
+package lib_test
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/tokiwong/helm-switcher/lib"
+	"github.com/tokiwong/helm-switcher/modal"
+)
+
+// TestSelfUpdate : the binary behind the symlink is replaced only when its checksum matches
+func TestSelfUpdate(t *testing.T) {
+
+	name := lib.SelfAssetName(runtime.GOOS, runtime.GOARCH)
+	binary := []byte("new helmswitch")
+	checksums := fmt.Sprintf("%x  %s\n", sha256.Sum256(binary), name)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/" + name:
+			w.Write(binary)
+		case "/checksums.txt":
+			fmt.Fprint(w, checksums)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	root, err := ioutil.TempDir("", "helmswitch-selfupdate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	target := filepath.Join(root, "helmswitch-0.0.5")
+	executable := filepath.Join(root, "helmswitch")
+	ioutil.WriteFile(target, []byte("old helmswitch"), 0755)
+	os.Symlink(target, executable)
+
+	release := modal.Repo{TagName: "v0.0.6", Assets: []modal.Assets{
+		{Name: name, BrowserDownloadURL: server.URL + "/" + name},
+		{Name: "checksums.txt", BrowserDownloadURL: server.URL + "/checksums.txt"},
+	}}
+
+	if lib.SelfUpdateAvailable("0.0.5", release) && !lib.SelfUpdateAvailable("0.0.6", release) {
+		t.Log("Only newer releases are updates [expected]")
+	} else {
+		t.Error("Unexpected update availability [unexpected]")
+	}
+
+	if _, err := lib.SelfUpdate(release, executable); err != nil {
+		t.Fatal(err)
+	}
+	if content, _ := ioutil.ReadFile(target); string(content) == string(binary) {
+		t.Log("Binary replaced behind the symlink [expected]")
+	} else {
+		t.Errorf("Unexpected binary %q [unexpected]", content)
+	}
+
+	checksums = fmt.Sprintf("%064d  %s\n", 0, name)
+	ioutil.WriteFile(target, []byte("old helmswitch"), 0755)
+	if _, err := lib.SelfUpdate(release, executable); err != nil {
+		t.Logf("Checksum mismatch refused: %v [expected]", err)
+	} else {
+		t.Error("Checksum mismatch accepted [unexpected]")
+	}
+	if content, _ := ioutil.ReadFile(target); string(content) == "old helmswitch" {
+		t.Log("Binary kept after a failed update [expected]")
+	} else {
+		t.Errorf("Unexpected binary %q [unexpected]", content)
+	}
+
+	release.Assets = release.Assets[:1]
+	if _, err := lib.SelfUpdate(release, executable); err != nil {
+		t.Logf("Release without checksums refused: %v [expected]", err)
+	} else {
+		t.Error("Release without checksums accepted [unexpected]")
+	}
+}
@@ -0,0 +1,314 @@
+package lib
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+)
+
+const (
+	stateFile          = "state.json"
+	stateSchemaVersion = 1
+)
+
+// ErrNoChecksum : the version was installed before checksums were recorded
+var ErrNoChecksum = errors.New("no checksum recorded")
+
+// InstalledVersion : what the store knows about an installed version
+type InstalledVersion struct {
+	Version       string    `json:"version"`
+	Binary        string    `json:"binary"`
+	SourceURL     string    `json:"source_url,omitempty"`
+	OS            string    `json:"os"`
+	Arch          string    `json:"arch"`
+	ArchiveSHA256 string    `json:"archive_sha256,omitempty"`
+	BinarySHA256  string    `json:"binary_sha256,omitempty"`
+	Size          int64     `json:"size"`
+	InstalledAt   time.Time `json:"installed_at"`
+	LastUsedAt    time.Time `json:"last_used_at"`
+	Links         []string  `json:"links"`
+}
+
+// State : versioned description of the store, kept in state.json in the install dir
+type State struct {
+	SchemaVersion int                          `json:"schema_version"`
+	Versions      map[string]*InstalledVersion `json:"versions"`
+}
+
+// LoadState : read state.json, building it from the binaries of the store when it does not exist yet
+func LoadState(dir string) (*State, error) {
+	content, err := ioutil.ReadFile(filepath.Join(dir, stateFile))
+	if os.IsNotExist(err) {
+		return migrateState(dir)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s := &State{}
+	if err := json.Unmarshal(content, s); err != nil {
+		return nil, fmt.Errorf("corrupt %s: %v", stateFile, err)
+	}
+	if s.SchemaVersion > stateSchemaVersion {
+		return nil, fmt.Errorf("%s has schema version %d, this helmswitch only understands up to %d, please upgrade", stateFile, s.SchemaVersion, stateSchemaVersion)
+	}
+	if s.Versions == nil {
+		s.Versions = map[string]*InstalledVersion{}
+	}
+	return s, nil
+}
+
+// migrateState : describe an existing store from its helm_x.x.x binaries, installed before checksums were recorded
+func migrateState(dir string) (*State, error) {
+	s := &State{SchemaVersion: stateSchemaVersion, Versions: map[string]*InstalledVersion{}}
+
+	versions := scanInstalledVersions(dir)
+	if len(versions) == 0 {
+		return s, nil
+	}
+	Log.Verbosef("Migrating %s to %s", dir, stateFile)
+
+	for _, version := range versions {
+		binary := filepath.Join(dir, activeTool.Prefix()+version)
+		info, err := os.Stat(binary)
+		if err != nil {
+			continue
+		}
+
+		v := &InstalledVersion{
+			Version:     version,
+			Binary:      binary,
+			OS:          runtime.GOOS,
+			Arch:        runtime.GOARCH,
+			Size:        info.Size(),
+			InstalledAt: info.ModTime().UTC(),
+			Links:       []string{},
+		}
+		s.Versions[version] = v
+	}
+
+	/* the link currently managed by helmswitch */
+	if active := ActiveVersion(installedBinPath, dir); active != "" {
+		if v, ok := s.Versions[active]; ok {
+			v.Links = append(v.Links, installedBinPath)
+		}
+	}
+
+	if err := s.Save(dir); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Save : write state.json atomically
+func (s *State) Save(dir string) error {
+	s.SchemaVersion = stateSchemaVersion
+
+	content, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := filepath.Join(dir, stateFile+".tmp")
+	Log.Debugf("write %s", filepath.Join(dir, stateFile))
+	if err := ioutil.WriteFile(tmp, append(content, '\n'), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(dir, stateFile))
+}
+
+// Installed : installed versions, newest first
+func (s *State) Installed() []string {
+	semvers := []*Version{}
+	for version := range s.Versions {
+		if sv, err := NewVersion(version); err == nil {
+			semvers = append(semvers, sv)
+		}
+	}
+	Sort(semvers)
+
+	versions := []string{}
+	for _, sv := range semvers {
+		versions = append(versions, sv.String())
+	}
+	return versions
+}
+
+// setLink : point link at version, removing it from every other version
+func (s *State) setLink(version string, link string) {
+	for _, v := range s.Versions {
+		links := []string{}
+		for _, l := range v.Links {
+			if l != link {
+				links = append(links, l)
+			}
+		}
+		v.Links = links
+	}
+	if v, ok := s.Versions[version]; ok && link != "" {
+		v.Links = append(v.Links, link)
+	}
+}
+
+// RecordInstall : add a freshly installed binary to the state, with its source and checksums
+func RecordInstall(dir string, version string, sourceURL string, archiveSHA256 string, link string) error {
+	s, err := LoadState(dir)
+	if err != nil {
+		return err
+	}
+
+	binary := filepath.Join(dir, activeTool.Prefix()+version)
+	info, err := os.Stat(binary)
+	if err != nil {
+		return err
+	}
+	sum, err := FileChecksum(binary)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	s.Versions[version] = &InstalledVersion{
+		Version:       version,
+		Binary:        binary,
+		SourceURL:     sourceURL,
+		OS:            runtime.GOOS,
+		Arch:          runtime.GOARCH,
+		ArchiveSHA256: archiveSHA256,
+		BinarySHA256:  sum,
+		Size:          info.Size(),
+		InstalledAt:   now,
+		LastUsedAt:    now,
+		Links:         []string{},
+	}
+	s.setLink(version, link)
+	return s.Save(dir)
+}
+
+// RecordSwitch : mark version as used now and owning link
+func RecordSwitch(dir string, version string, link string) error {
+	s, err := LoadState(dir)
+	if err != nil {
+		return err
+	}
+	v, ok := s.Versions[version]
+	if !ok {
+		return fmt.Errorf("%s is not installed", version)
+	}
+	v.LastUsedAt = time.Now().UTC()
+	s.setLink(version, link)
+	return s.Save(dir)
+}
+
+// VerifyInstalled : re-hash an installed binary and compare it with the checksum recorded at install
+func VerifyInstalled(dir string, version string) (string, error) {
+	s, err := LoadState(dir)
+	if err != nil {
+		return "", err
+	}
+
+	sum, err := FileChecksum(filepath.Join(dir, activeTool.Prefix()+version))
+	if err != nil {
+		return "", err
+	}
+
+	v, ok := s.Versions[version]
+	if !ok || v.BinarySHA256 == "" {
+		return sum, ErrNoChecksum
+	}
+
+	if v.BinarySHA256 != sum {
+		return sum, fmt.Errorf("binary %s%s has been modified: expected sha256 %s, found %s", activeTool.Prefix(), version, v.BinarySHA256, sum)
+	}
+	return sum, nil
+}
+
+// RemoveInstalled : delete an installed binary and forget about it
+func RemoveInstalled(dir string, version string) error {
+	s, err := LoadState(dir)
+	if err != nil {
+		return err
+	}
+	binary := filepath.Join(dir, activeTool.Prefix()+version)
+	if CheckFileExist(binary) {
+		RemoveFiles(binary)
+	}
+	for _, name := range activeTool.Companions(version) {
+		if companion := CompanionBinary(dir, name, version); CheckFileExist(companion) {
+			RemoveFiles(companion)
+		}
+	}
+	delete(s.Versions, version)
+	return s.Save(dir)
+}
+
+// StateDrift : versions recorded without a binary, and helm_x.x.x binaries the state does not know about
+func StateDrift(dir string) ([]string, []string, error) {
+	s, err := LoadState(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	missing := []string{}
+	for _, version := range s.Installed() {
+		if !CheckFileExist(filepath.Join(dir, activeTool.Prefix()+version)) {
+			missing = append(missing, version)
+		}
+	}
+
+	untracked := []string{}
+	for _, version := range scanInstalledVersions(dir) {
+		if _, ok := s.Versions[version]; !ok {
+			untracked = append(untracked, version)
+		}
+	}
+	return missing, untracked, nil
+}
+
+// ReconcileState : forget versions whose binary is gone and adopt untracked binaries, without checksum
+func ReconcileState(dir string) error {
+	missing, untracked, err := StateDrift(dir)
+	if err != nil {
+		return err
+	}
+	s, err := LoadState(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, version := range missing {
+		delete(s.Versions, version)
+	}
+	for _, version := range untracked {
+		binary := filepath.Join(dir, activeTool.Prefix()+version)
+		info, err := os.Stat(binary)
+		if err != nil {
+			continue
+		}
+		s.Versions[version] = &InstalledVersion{
+			Version:     version,
+			Binary:      binary,
+			OS:          runtime.GOOS,
+			Arch:        runtime.GOARCH,
+			Size:        info.Size(),
+			InstalledAt: info.ModTime().UTC(),
+			Links:       []string{},
+		}
+	}
+	return s.Save(dir)
+}
+
+// ListInstalledVersions : installed versions recorded in the state of dir, newest first
+func ListInstalledVersions(dir string) []string {
+	s, err := LoadState(dir)
+	if err != nil {
+		Report.Warn("%v", err)
+		return []string{}
+	}
+	return s.Installed()
+}
@@ -0,0 +1,153 @@
+package lib_test
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/tokiwong/helm-switcher/lib"
+)
+
+// TestLoadState_Migrate : a store without state.json is described from its binaries
+func TestLoadState_Migrate(t *testing.T) {
+
+	installDir, err := ioutil.TempDir("", "helmswitch-state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(installDir)
+
+	ioutil.WriteFile(installDir+"/helm_2.16.1", []byte("helm 2"), 0755)
+	ioutil.WriteFile(installDir+"/helm_3.3.0", []byte("helm 3"), 0755)
+
+	s, err := lib.LoadState(installDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if installed := s.Installed(); len(installed) == 2 && installed[0] == "3.3.0" {
+		t.Logf("Migrated versions %v [expected]", installed)
+	} else {
+		t.Errorf("Unexpected migrated versions %v [unexpected]", installed)
+	}
+
+	if v := s.Versions["2.16.1"]; v != nil && v.Size == int64(len("helm 2")) && v.BinarySHA256 == "" {
+		t.Log("Binary described without a checksum [expected]")
+	} else {
+		t.Errorf("Unexpected description %+v [unexpected]", v)
+	}
+
+	if _, err := lib.VerifyInstalled(installDir, "3.3.0"); err == lib.ErrNoChecksum {
+		t.Log("Migrated version has no recorded checksum [expected]")
+	} else {
+		t.Errorf("Unexpected verification of a migrated version: %v [unexpected]", err)
+	}
+
+	if checkFileExist(installDir + "/state.json") {
+		t.Log("state.json written [expected]")
+	} else {
+		t.Error("state.json not written [unexpected]")
+	}
+}
+
+// TestVerifyInstalled : record an install, check it verifies, modify the binary, check it fails
+func TestVerifyInstalled(t *testing.T) {
+
+	installDir, err := ioutil.TempDir("", "helmswitch-state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(installDir)
+
+	ioutil.WriteFile(installDir+"/helm_3.3.0", []byte("helm binary"), 0755)
+
+	if _, err := lib.VerifyInstalled(installDir, "3.3.0"); err == lib.ErrNoChecksum {
+		t.Log("No checksum for a binary copied by hand [expected]")
+	} else {
+		t.Errorf("Expected missing checksum, got %v [unexpected]", err)
+	}
+
+	if err := lib.RecordInstall(installDir, "3.3.0", "https://get.helm.sh/helm-v3.3.0-linux-amd64.tar.gz", "archivesum", "/usr/local/bin/helm"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := lib.VerifyInstalled(installDir, "3.3.0"); err == nil {
+		t.Log("Binary verified [expected]")
+	} else {
+		t.Errorf("Binary should verify: %v [unexpected]", err)
+	}
+
+	ioutil.WriteFile(installDir+"/helm_3.3.0", []byte("tampered binary"), 0755)
+
+	if _, err := lib.VerifyInstalled(installDir, "3.3.0"); err != nil && err != lib.ErrNoChecksum {
+		t.Logf("Tampering detected: %v [expected]", err)
+	} else {
+		t.Error("Tampered binary should not verify [unexpected]")
+	}
+}
+
+// TestRecordSwitch : the link moves to the version switched to
+func TestRecordSwitch(t *testing.T) {
+
+	installDir, err := ioutil.TempDir("", "helmswitch-state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(installDir)
+
+	link := "/usr/local/bin/helm"
+	ioutil.WriteFile(installDir+"/helm_2.16.1", []byte("helm 2"), 0755)
+	ioutil.WriteFile(installDir+"/helm_3.3.0", []byte("helm 3"), 0755)
+	lib.RecordInstall(installDir, "2.16.1", "", "", link)
+	lib.RecordInstall(installDir, "3.3.0", "", "", link)
+
+	if err := lib.RecordSwitch(installDir, "2.16.1", link); err != nil {
+		t.Fatal(err)
+	}
+
+	s, _ := lib.LoadState(installDir)
+	if len(s.Versions["2.16.1"].Links) == 1 && len(s.Versions["3.3.0"].Links) == 0 {
+		t.Log("Link moved to 2.16.1 [expected]")
+	} else {
+		t.Errorf("Links not updated: %v %v [unexpected]", s.Versions["2.16.1"].Links, s.Versions["3.3.0"].Links)
+	}
+
+	if err := lib.RecordSwitch(installDir, "1.0.0", link); err != nil {
+		t.Logf("Switch to a version not installed refused: %v [expected]", err)
+	} else {
+		t.Error("Switch to a version not installed should fail [unexpected]")
+	}
+}
+
+// TestReconcileState : binaries removed or copied by hand are picked up
+func TestReconcileState(t *testing.T) {
+
+	installDir, err := ioutil.TempDir("", "helmswitch-state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(installDir)
+
+	ioutil.WriteFile(installDir+"/helm_3.3.0", []byte("helm 3"), 0755)
+	lib.RecordInstall(installDir, "3.3.0", "", "", "")
+	os.Remove(installDir + "/helm_3.3.0")
+	ioutil.WriteFile(installDir+"/helm_3.2.4", []byte("helm 3"), 0755)
+
+	missing, untracked, err := lib.StateDrift(installDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(missing) == 1 && missing[0] == "3.3.0" && len(untracked) == 1 && untracked[0] == "3.2.4" {
+		t.Log("Drift detected [expected]")
+	} else {
+		t.Errorf("Unexpected drift missing=%v untracked=%v [unexpected]", missing, untracked)
+	}
+
+	lib.ReconcileState(installDir)
+
+	if installed := lib.ListInstalledVersions(installDir); len(installed) == 1 && installed[0] == "3.2.4" {
+		t.Log("State reconciled [expected]")
+	} else {
+		t.Errorf("State not reconciled: %v [unexpected]", installed)
+	}
+}
@@ -1,7 +1,6 @@
 package lib
 
 import (
-	"log"
 	"os"
 )
 
@@ -9,9 +8,10 @@ import (
 //CreateSymlink : create symlink
 func CreateSymlink(cwd string, dir string) {
 
+	Log.Debugf("symlink %s -> %s", dir, cwd)
 	err := os.Symlink(cwd, dir)
 	if err != nil {
-		log.Fatalf(`
+		Fail(`
 		Unable to create new symlink.
 		Maybe symlink already exist. Try removing existing symlink manually.
 		Try running "unlink" to remove existing symlink.
@@ -27,7 +27,7 @@ func RemoveSymlink(symlinkPath string) {
 
 	_, err := os.Lstat(symlinkPath)
 	if err != nil {
-		log.Fatalf(`
+		Fail(`
 		Unable to remove symlink.
 		Maybe symlink already exist. Try removing existing symlink manually.
 		Try running "unlink" to remove existing symlink.
@@ -36,9 +36,10 @@ func RemoveSymlink(symlinkPath string) {
 		`, symlinkPath, err)
 		os.Exit(1)
 	} else {
+		Log.Debugf("remove symlink %s", symlinkPath)
 		errRemove := os.Remove(symlinkPath)
 		if errRemove != nil {
-			log.Fatalf(`
+			Fail(`
 			Unable to remove symlink.
 			Maybe symlink already exist. Try removing existing symlink manually.
 			Try running "unlink" to remove existing symlink.
@@ -0,0 +1,304 @@
+package lib
+
+import (
+	"bytes"
+	"fmt"
+	"os/user"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/tokiwong/helm-switcher/modal"
+)
+
+// Archive layouts of the downloaded artifact
+const (
+	LayoutTarGz  = "tar.gz"
+	LayoutTarXz  = "tar.xz"
+	LayoutTarBz2 = "tar.bz2"
+	LayoutZip    = "zip"
+	LayoutBinary = "binary"
+	/* detected from the content of the download, or its extension */
+	LayoutAuto = "auto"
+)
+
+// Checksum formats of the published checksum file
+const (
+	/* the sum alone, optionally followed by the file name, eg. helm-v3.3.0-linux-amd64.tar.gz.sha256 */
+	ChecksumSHA256 = "sha256"
+	/* sha256sum output listing every artifact of the release, eg. checksums.txt */
+	ChecksumSHA256Sums = "sha256sums"
+)
+
+// Tool : a command line tool whose versions helmswitch installs and switches between
+//
+// The artifact is downloaded from URL, or from the release asset named Asset, its checksum
+// from Checksum or the asset named ChecksumAsset; none of them means no checksum is published.
+// They and Binary are templates with .Version, .Tag, .OS and .Arch, OS and Arch mapping the
+// Go names to the ones the tool uses; Checksum also gets the artifact .URL.
+// CompanionVersions names the other binaries of the archive, found next to Binary, with the
+// versions shipping them.
+type Tool struct {
+	Name           string            `yaml:"name"`
+	Repo           string            `yaml:"repo"`
+	API            string            `yaml:"api"`
+	TagPrefix      string            `yaml:"tag_prefix"`
+	URL            string            `yaml:"url"`
+	Asset          string            `yaml:"asset"`
+	OS             map[string]string `yaml:"os"`
+	Arch           map[string]string `yaml:"arch"`
+	Layout         string            `yaml:"layout"`
+	Binary         string            `yaml:"binary"`
+	Checksum       string            `yaml:"checksum"`
+	ChecksumAsset  string            `yaml:"checksum_asset"`
+	ChecksumFormat string            `yaml:"checksum_format"`
+
+	/* other binaries of the archive, kept and linked for the versions matching the constraint */
+	CompanionVersions map[string]string `yaml:"companions"`
+}
+
+// Tools : the tools helmswitch knows about
+var Tools = map[string]*Tool{
+	"helm": {
+		Name:           "helm",
+		Repo:           "helm/helm",
+		TagPrefix:      "v",
+		URL:            "https://get.helm.sh/helm-{{.Tag}}-{{.OS}}-{{.Arch}}.tar.gz",
+		Layout:         LayoutAuto,
+		Binary:         "{{.OS}}-{{.Arch}}/helm",
+		Checksum:       "{{.URL}}.sha256",
+		ChecksumFormat: ChecksumSHA256,
+		/* Helm 2 ships the tiller server in the same archive */
+		CompanionVersions: map[string]string{"tiller": "< 3.0.0"},
+	},
+	"kubectl": {
+		Name:           "kubectl",
+		Repo:           "kubernetes/kubernetes",
+		TagPrefix:      "v",
+		URL:            "https://dl.k8s.io/release/{{.Tag}}/bin/{{.OS}}/{{.Arch}}/kubectl",
+		Layout:         LayoutBinary,
+		Checksum:       "{{.URL}}.sha256",
+		ChecksumFormat: ChecksumSHA256,
+	},
+	"helmfile": {
+		Name:      "helmfile",
+		Repo:      "roboll/helmfile",
+		TagPrefix: "v",
+		URL:       "https://github.com/roboll/helmfile/releases/download/{{.Tag}}/helmfile_{{.OS}}_{{.Arch}}",
+		Layout:    LayoutBinary,
+	},
+	"kustomize": {
+		Name:           "kustomize",
+		Repo:           "kubernetes-sigs/kustomize",
+		TagPrefix:      "kustomize/v",
+		URL:            "https://github.com/kubernetes-sigs/kustomize/releases/download/kustomize%2Fv{{.Version}}/kustomize_v{{.Version}}_{{.OS}}_{{.Arch}}.tar.gz",
+		Layout:         LayoutTarGz,
+		Binary:         "kustomize",
+		Checksum:       "https://github.com/kubernetes-sigs/kustomize/releases/download/kustomize%2Fv{{.Version}}/checksums.txt",
+		ChecksumFormat: ChecksumSHA256Sums,
+	},
+}
+
+/* the tool this run manages, helm unless --tool says otherwise */
+var activeTool = Tools["helm"]
+
+// ToolNames : names of the known tools, sorted
+func ToolNames() []string {
+	names := []string{}
+	for name := range Tools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LookupTool : the tool called name
+func LookupTool(name string) (*Tool, error) {
+	if t, ok := Tools[name]; ok {
+		return t, nil
+	}
+	return nil, fmt.Errorf("unknown tool %q, expecting one of %s", name, strings.Join(ToolNames(), ", "))
+}
+
+// UseTool : manage t for the rest of the run, with its own store and binary path
+func UseTool(t *Tool) {
+	activeTool = t
+
+	/* get current user */
+	usr, errCurr := user.Current()
+	if errCurr != nil {
+		Fail("%v", errCurr)
+	}
+
+	/* set installation location */
+	installLocation = t.StoreDir(usr.HomeDir)
+
+	/* set default binary path, next to the default helm */
+	installedBinPath = filepath.Join(filepath.Dir(binLocation), t.Name)
+
+	/* overrride installation default binary path if the tool is already installed */
+	/* find the last bin path */
+	next := NewCommand(t.Name).Find()
+	for path := next(); len(path) > 0; path = next() {
+		installedBinPath = path
+	}
+
+	/* Create local installation directory if it does not exist */
+	CreateDirIfNotExist(installLocation)
+}
+
+// ActiveTool : the tool this run manages
+func ActiveTool() *Tool {
+	return activeTool
+}
+
+// StoreDir : directory holding the installed versions of the active tool
+func StoreDir() string {
+	return installLocation
+}
+
+// DefaultBinPath : where the active tool is linked when --bin is not given
+func DefaultBinPath() string {
+	return installedBinPath
+}
+
+// StoreDir : directory holding the installed versions of the tool, helm keeps the top level of the store
+func (t *Tool) StoreDir(home string) string {
+	if t.Name == "helm" {
+		return home + installPath
+	}
+	return home + installPath + t.Name + "/"
+}
+
+// Prefix : file name prefix of the installed versions, eg. helm_ for helm_3.3.0
+func (t *Tool) Prefix() string {
+	return t.Name + "_"
+}
+
+// ReleasesURL : GitHub api url listing the releases of the tool
+func (t *Tool) ReleasesURL() string {
+	api := "https://api.github.com"
+	if t.API != "" {
+		api = strings.TrimSuffix(t.API, "/")
+	}
+	return api + "/repos/" + t.Repo + "/releases?"
+}
+
+// TagVersion : the version of a release tag, false for tags of other components or pre-releases
+func (t *Tool) TagVersion(tag string) (string, bool) {
+	if !strings.HasPrefix(tag, t.TagPrefix) {
+		return "", false
+	}
+	version := strings.TrimPrefix(tag, t.TagPrefix)
+	return version, regexp.MustCompile(`\A\d+(\.\d+){2}\z`).MatchString(version)
+}
+
+// ArtifactURL : where the release is downloaded from for a platform
+//
+// The asset template wins; otherwise the release assets that are artifacts of the tool are matched
+// against the platform, and the url template is used for releases without any.
+func (t *Tool) ArtifactURL(release modal.Repo, goos string, goarch string) (string, error) {
+	version, _ := t.TagVersion(release.TagName)
+	if t.Asset != "" {
+		name, err := t.render("asset", t.Asset, version, goos, goarch, "")
+		if err != nil {
+			return "", err
+		}
+		return releaseAssetURL(release, name)
+	}
+	if url, ok, err := t.matchAsset(release, goos, goarch); ok {
+		return url, err
+	}
+	return t.render("url", t.URL, version, goos, goarch, "")
+}
+
+// ChecksumURL : where the checksum of the artifact is published, empty when it is not
+func (t *Tool) ChecksumURL(release modal.Repo, goos string, goarch string) (string, error) {
+	if t.Checksum == "" && t.ChecksumAsset == "" {
+		return "", nil
+	}
+	artifact, err := t.ArtifactURL(release, goos, goarch)
+	if err != nil {
+		return "", err
+	}
+	version, _ := t.TagVersion(release.TagName)
+	if t.ChecksumAsset != "" {
+		name, err := t.render("checksum_asset", t.ChecksumAsset, version, goos, goarch, artifact)
+		if err != nil {
+			return "", err
+		}
+		return releaseAssetURL(release, name)
+	}
+	return t.render("checksum", t.Checksum, version, goos, goarch, artifact)
+}
+
+// releaseAssetURL : download url of the asset called name
+func releaseAssetURL(release modal.Repo, name string) (string, error) {
+	for _, asset := range release.Assets {
+		if asset.Name == name {
+			return asset.BrowserDownloadURL, nil
+		}
+	}
+	return "", fmt.Errorf("release %s has no asset named %s", release.TagName, name)
+}
+
+// BinaryPath : path of the binary inside the archive
+func (t *Tool) BinaryPath(version string, goos string, goarch string) (string, error) {
+	if t.Binary == "" {
+		return t.Name, nil
+	}
+	return t.render("binary", t.Binary, version, goos, goarch, "")
+}
+
+// render : expand one of the templates of the tool
+func (t *Tool) render(field string, text string, version string, goos string, goarch string, artifact string) (string, error) {
+	tmpl, err := template.New(field).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("%s: invalid %s template: %v", t.Name, field, err)
+	}
+
+	/* the names the tool uses for the platform, eg. x86_64 for amd64 */
+	if name, ok := t.OS[goos]; ok {
+		goos = name
+	}
+	if name, ok := t.Arch[goarch]; ok {
+		goarch = name
+	}
+
+	var buffer bytes.Buffer
+	err = tmpl.Execute(&buffer, struct {
+		Version, Tag, OS, Arch, URL string
+	}{version, t.TagPrefix + version, goos, goarch, artifact})
+	if err != nil {
+		return "", fmt.Errorf("%s: invalid %s template: %v", t.Name, field, err)
+	}
+	return buffer.String(), nil
+}
+
+// ReleaseVersion : the version of a release of the active tool
+func ReleaseVersion(r modal.Repo) string {
+	if version, ok := activeTool.TagVersion(r.TagName); ok {
+		return version
+	}
+	return strings.TrimPrefix(path.Base(r.TagName), "v")
+}
+
+// ParseChecksum : the sha256 of fileName in the content of a checksum file
+func ParseChecksum(content string, fileName string, format string) (string, error) {
+	for _, line := range strings.Split(content, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || len(fields[0]) != 64 {
+			continue
+		}
+		if len(fields) == 1 && format != ChecksumSHA256Sums {
+			return fields[0], nil
+		}
+		if len(fields) == 2 && path.Base(strings.TrimPrefix(fields[1], "*")) == fileName {
+			return fields[0], nil
+		}
+	}
+	return "", fmt.Errorf("no sha256 for %s in checksum file", fileName)
+}
@@ -0,0 +1,117 @@
+package lib
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+
+	"github.com/tokiwong/helm-switcher/modal"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// toolsDir : directory of the config dir holding the tool manifests
+const toolsDir = "tools"
+
+var toolNameRegex = regexp.MustCompile(`\A[a-z0-9][a-z0-9_-]*\z`)
+
+// ToolsDir : directory the tool manifests are read from
+func ToolsDir() string {
+	return filepath.Join(ConfigDir(), toolsDir)
+}
+
+// LoadToolManifest : read and check the tool described by a YAML manifest
+func LoadToolManifest(path string) (*Tool, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	/* defaults for the fields a manifest leaves out */
+	t := &Tool{TagPrefix: "v", Layout: LayoutAuto, ChecksumFormat: ChecksumSHA256}
+	if err := yaml.UnmarshalStrict(content, t); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if err := t.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return t, nil
+}
+
+// LoadToolManifests : add the tools described in dir/*.yaml to Tools, warning about the invalid ones
+func LoadToolManifests(dir string) {
+	paths, _ := filepath.Glob(filepath.Join(dir, "*.yaml"))
+	more, _ := filepath.Glob(filepath.Join(dir, "*.yml"))
+	paths = append(paths, more...)
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		t, err := LoadToolManifest(path)
+		if err != nil {
+			Report.Warn("ignoring tool manifest %v", err)
+			continue
+		}
+		if _, ok := Tools[t.Name]; ok {
+			Log.Debugf("%s replaces the definition of %s", path, t.Name)
+		}
+		Log.Debugf("tool %s from %s", t.Name, path)
+		Tools[t.Name] = t
+	}
+}
+
+// Validate : check the tool has a name, a release source and templates that expand
+func (t *Tool) Validate() error {
+	if !toolNameRegex.MatchString(t.Name) {
+		return fmt.Errorf("invalid tool name %q", t.Name)
+	}
+	if t.Repo == "" {
+		return fmt.Errorf("%s: repo is required to list the releases", t.Name)
+	}
+	if (t.URL == "") == (t.Asset == "") {
+		return fmt.Errorf("%s: set either url or asset", t.Name)
+	}
+	if t.Checksum != "" && t.ChecksumAsset != "" {
+		return fmt.Errorf("%s: set either checksum or checksum_asset", t.Name)
+	}
+	switch t.Layout {
+	case LayoutAuto, LayoutTarGz, LayoutTarXz, LayoutTarBz2, LayoutZip, LayoutBinary:
+	default:
+		return fmt.Errorf("%s: unknown layout %q", t.Name, t.Layout)
+	}
+	if (t.Checksum != "" || t.ChecksumAsset != "") && t.ChecksumFormat != ChecksumSHA256 && t.ChecksumFormat != ChecksumSHA256Sums {
+		return fmt.Errorf("%s: unknown checksum_format %q", t.Name, t.ChecksumFormat)
+	}
+
+	for name, versions := range t.CompanionVersions {
+		if !toolNameRegex.MatchString(name) || name == t.Name {
+			return fmt.Errorf("%s: invalid companion name %q", t.Name, name)
+		}
+		if _, err := ParseConstraint(versions); err != nil {
+			return fmt.Errorf("%s: companion %s: %v", t.Name, name, err)
+		}
+	}
+
+	/* expand every template once, against a release carrying the asset names */
+	sample := modal.Repo{TagName: t.TagPrefix + "1.0.0"}
+	for _, text := range []string{t.Asset, t.ChecksumAsset} {
+		if text == "" {
+			continue
+		}
+		name, err := t.render("asset", text, "1.0.0", "linux", "amd64", "")
+		if err != nil {
+			return err
+		}
+		sample.Assets = append(sample.Assets, modal.Assets{Name: name})
+	}
+	if _, err := t.ChecksumURL(sample, "linux", "amd64"); err != nil {
+		return err
+	}
+	if _, err := t.ArtifactURL(sample, "linux", "amd64"); err != nil {
+		return err
+	}
+	if _, err := t.BinaryPath("1.0.0", "linux", "amd64"); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package lib_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tokiwong/helm-switcher/lib"
+	"github.com/tokiwong/helm-switcher/modal"
+)
+
+const testToolManifest = `name: mytool
+repo: example/mytool
+asset: mytool_{{.OS}}_{{.Arch}}.tar.gz
+arch:
+  amd64: x86_64
+binary: bin/mytool
+checksum_asset: checksums.txt
+checksum_format: sha256sums
+`
+
+// TestLoadToolManifest : a manifest describes a tool downloaded from its release assets
+func TestLoadToolManifest(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "helmswitch-tools")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ioutil.WriteFile(filepath.Join(dir, "mytool.yaml"), []byte(testToolManifest), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "broken.yml"), []byte("name: broken\nrepo: example/broken\n"), 0644)
+
+	lib.LoadToolManifests(dir)
+	defer delete(lib.Tools, "mytool")
+
+	tool, err := lib.LookupTool("mytool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := lib.LookupTool("broken"); err != nil {
+		t.Log("Invalid manifest skipped [expected]")
+	} else {
+		t.Error("Invalid manifest registered [unexpected]")
+	}
+
+	release := modal.Repo{TagName: "v1.2.0", Assets: []modal.Assets{
+		{Name: "mytool_linux_x86_64.tar.gz", BrowserDownloadURL: "https://example.com/mytool_linux_x86_64.tar.gz"},
+		{Name: "checksums.txt", BrowserDownloadURL: "https://example.com/checksums.txt"},
+	}}
+
+	url, err := tool.ArtifactURL(release, "linux", "amd64")
+	if err == nil && url == "https://example.com/mytool_linux_x86_64.tar.gz" {
+		t.Log("Artifact found in the release assets [expected]")
+	} else {
+		t.Errorf("Unexpected artifact %q %v [unexpected]", url, err)
+	}
+	if checksum, err := tool.ChecksumURL(release, "linux", "amd64"); err == nil && checksum == "https://example.com/checksums.txt" {
+		t.Log("Checksum found in the release assets [expected]")
+	} else {
+		t.Errorf("Unexpected checksum %q %v [unexpected]", checksum, err)
+	}
+	if _, err := tool.ArtifactURL(release, "darwin", "amd64"); err != nil {
+		t.Logf("Missing asset reported: %v [expected]", err)
+	} else {
+		t.Error("Missing asset not reported [unexpected]")
+	}
+}
+
+// TestToolValidate : manifests missing a source or with broken templates are refused
+func TestToolValidate(t *testing.T) {
+
+	invalid := []lib.Tool{
+		{Name: "x", URL: "https://example.com/x", Layout: lib.LayoutBinary, ChecksumFormat: lib.ChecksumSHA256},
+		{Name: "x", Repo: "a/x", Layout: lib.LayoutBinary, ChecksumFormat: lib.ChecksumSHA256},
+		{Name: "x", Repo: "a/x", URL: "https://example.com/{{.Nope}}", Layout: lib.LayoutBinary, ChecksumFormat: lib.ChecksumSHA256},
+		{Name: "x", Repo: "a/x", URL: "https://example.com/x", Layout: "rar", ChecksumFormat: lib.ChecksumSHA256},
+	}
+	for _, tool := range invalid {
+		if err := tool.Validate(); err != nil {
+			t.Logf("Refused: %v [expected]", err)
+		} else {
+			t.Errorf("Accepted %+v [unexpected]", tool)
+		}
+	}
+
+	for _, name := range lib.ToolNames() {
+		tool, _ := lib.LookupTool(name)
+		if err := tool.Validate(); err != nil {
+			t.Errorf("Built in tool %s is invalid: %v [unexpected]", name, err)
+		}
+	}
+}
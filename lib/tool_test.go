@@ -0,0 +1,92 @@
+package lib_test
+
+import (
+	"testing"
+
+	"github.com/tokiwong/helm-switcher/lib"
+	"github.com/tokiwong/helm-switcher/modal"
+)
+
+// TestToolURLs : artifact, checksum and binary templates of the built in tools
+func TestToolURLs(t *testing.T) {
+
+	cases := []struct {
+		tool     string
+		url      string
+		checksum string
+		binary   string
+	}{
+		{"helm", "https://get.helm.sh/helm-v3.3.0-linux-amd64.tar.gz", "https://get.helm.sh/helm-v3.3.0-linux-amd64.tar.gz.sha256", "linux-amd64/helm"},
+		{"kubectl", "https://dl.k8s.io/release/v3.3.0/bin/linux/amd64/kubectl", "https://dl.k8s.io/release/v3.3.0/bin/linux/amd64/kubectl.sha256", "kubectl"},
+		{"helmfile", "https://github.com/roboll/helmfile/releases/download/v3.3.0/helmfile_linux_amd64", "", "helmfile"},
+		{"kustomize", "https://github.com/kubernetes-sigs/kustomize/releases/download/kustomize%2Fv3.3.0/kustomize_v3.3.0_linux_amd64.tar.gz", "https://github.com/kubernetes-sigs/kustomize/releases/download/kustomize%2Fv3.3.0/checksums.txt", "kustomize"},
+	}
+
+	for _, c := range cases {
+		tool, err := lib.LookupTool(c.tool)
+		if err != nil {
+			t.Fatal(err)
+		}
+		release := modal.Repo{TagName: tool.TagPrefix + "3.3.0"}
+		url, _ := tool.ArtifactURL(release, "linux", "amd64")
+		checksum, _ := tool.ChecksumURL(release, "linux", "amd64")
+		binary, _ := tool.BinaryPath("3.3.0", "linux", "amd64")
+
+		if url == c.url && checksum == c.checksum && binary == c.binary {
+			t.Logf("%s urls [expected]", c.tool)
+		} else {
+			t.Errorf("Unexpected %s urls %q %q %q [unexpected]", c.tool, url, checksum, binary)
+		}
+	}
+
+	if _, err := lib.LookupTool("terraform"); err != nil {
+		t.Logf("Unknown tool refused: %v [expected]", err)
+	} else {
+		t.Error("Unknown tool accepted [unexpected]")
+	}
+}
+
+// TestTagVersion : tags of other components and pre-releases are not versions of the tool
+func TestTagVersion(t *testing.T) {
+
+	kustomize, _ := lib.LookupTool("kustomize")
+
+	if version, ok := kustomize.TagVersion("kustomize/v3.8.1"); ok && version == "3.8.1" {
+		t.Log("Prefixed tag [expected]")
+	} else {
+		t.Errorf("Unexpected version %q [unexpected]", version)
+	}
+	for _, tag := range []string{"api/v0.6.0", "kustomize/v3.8.1-rc1", "v3.8.1"} {
+		if _, ok := kustomize.TagVersion(tag); !ok {
+			t.Logf("%s skipped [expected]", tag)
+		} else {
+			t.Errorf("%s accepted [unexpected]", tag)
+		}
+	}
+}
+
+// TestParseChecksum : a sum alone, or the line of the file in a sha256sum list
+func TestParseChecksum(t *testing.T) {
+
+	sum := "a6bd3a1b4ae8d4bc9c5de7d2b8f0b1e8d5c4b3a2f1e0d9c8b7a6f5e4d3c2b1a0"
+	other := "0000000000000000000000000000000000000000000000000000000000000000"
+
+	if got, err := lib.ParseChecksum(sum+"\n", "helm.tar.gz", lib.ChecksumSHA256); err == nil && got == sum {
+		t.Log("Sum alone [expected]")
+	} else {
+		t.Errorf("Unexpected %q %v [unexpected]", got, err)
+	}
+
+	list := other + "  kustomize_v3.8.1_darwin_amd64.tar.gz\n" + sum + "  kustomize_v3.8.1_linux_amd64.tar.gz\n"
+	if got, err := lib.ParseChecksum(list, "kustomize_v3.8.1_linux_amd64.tar.gz", lib.ChecksumSHA256Sums); err == nil && got == sum {
+		t.Log("Sum from list [expected]")
+	} else {
+		t.Errorf("Unexpected %q %v [unexpected]", got, err)
+	}
+
+	if _, err := lib.ParseChecksum(list, "kustomize_v3.8.1_windows_amd64.zip", lib.ChecksumSHA256Sums); err != nil {
+		t.Logf("Missing file refused: %v [expected]", err)
+	} else {
+		t.Error("Missing file accepted [unexpected]")
+	}
+}
@@ -2,10 +2,14 @@ package main
 
 import (
 	"fmt"
-	"log"
 	"os"
+	"os/exec"
 	"os/user"
+	"path/filepath"
 	"regexp"
+	"runtime"
+	"strings"
+	"time"
 
 	"github.com/manifoldco/promptui"
 	"github.com/pborman/getopt"
@@ -14,18 +18,30 @@ import (
 )
 
 const (
-	helmURL        = "https://api.github.com/repos/helm/helm/releases?"
-	defaultBin     = "/usr/local/bin/helm"
-	installFile    = "helm"
-	installVersion = "helm_"
-	installPath    = "/.helm.versions/"
+	defaultBin = "/usr/local/bin/helm"
 )
 
-var version = "0.0.5\n"
+/* build info, set at release time with
+ * -ldflags "-X main.version=0.0.6 -X main.commit=$(git rev-parse --short HEAD) -X main.date=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+ */
+var (
+	version = "0.0.5"
+	commit  = "none"
+	date    = "unknown"
+)
 
 var clientID = "xxx"
 var clientSecret = "xxx"
 
+/* user settings from config.yaml */
+var config = lib.DefaultConfig()
+
+/* banned and deprecated versions, --allow-insecure overrides the bans */
+var (
+	policy        = &lib.Policy{}
+	allowInsecure bool
+)
+
 func main() {
 
 	var client modal.Client
@@ -34,99 +50,1012 @@ func main() {
 	client.ClientSecret = clientSecret
 
 	custBinPath := getopt.StringLong("bin", 'b', defaultBin, "Custom binary path. For example: /Users/username/bin/helm")
+	toolName := getopt.StringLong("tool", 't', "helm", "tool to manage, helm by default, see the list of tools below", "name")
 	helpFlag := getopt.BoolLong("help", 'h', "displays help message")
 	versionFlag := getopt.BoolLong("version", 'v', "displays the version of helmswitch")
-	_ = versionFlag
+	quietFlag := getopt.BoolLong("quiet", 'q', "only print errors")
+	verboseFlag := getopt.BoolLong("verbose", 0, "print download sizes, checksums and other details")
+	debugFlag := getopt.BoolLong("debug", 0, "print http requests, rate limits, redirects and file operations")
+	outputFormat := "text"
+	getopt.EnumVarLong(&outputFormat, "output", 'o', []string{"text", "json"}, "output format: text or json", "format")
+
+	fixFlag := getopt.BoolLong("fix", 0, "doctor: apply the suggested fixes")
+	allFlag := getopt.BoolLong("all", 0, "verify: check every installed version")
+	getopt.BoolVarLong(&allowInsecure, "allow-insecure", 0, "switch to versions refused by the policy")
+	noVerifyFlag := getopt.BoolLong("no-verify", 0, "import: adopt binaries without comparing them with the published releases")
+	pinFormat := lib.PinFormatVersionFile
+	getopt.EnumVarLong(&pinFormat, "format", 0, []string{lib.PinFormatVersionFile, lib.PinFormatToolVersions}, "pin: write .helm-version (version-file) or the asdf .tool-versions", "format")
+
+	args := parseArgs()
+
+	switch {
+	case *debugFlag:
+		lib.Log.Level = lib.LevelDebug
+	case *verboseFlag:
+		lib.Log.Level = lib.LevelVerbose
+	case *quietFlag:
+		lib.Log.Level = lib.LevelQuiet
+	}
+
+	if outputFormat == "json" {
+		lib.EnableJSONOutput()
+	}
+
+	/* tools described by manifests in the config dir, eg. ~/.config/helmswitch/tools/terraform.yaml */
+	lib.LoadToolManifests(lib.ToolsDir())
+
+	tool, err := lib.LookupTool(*toolName)
+	if err != nil {
+		lib.Fail("%v", err)
+	}
+	lib.UseTool(tool)
+	if !getopt.IsSet("bin") {
+		*custBinPath = filepath.Join(filepath.Dir(defaultBin), tool.Name)
+	}
+
+	if cfg, err := lib.LoadConfig(lib.ConfigDir()); err != nil {
+		lib.Report.Warn("ignoring invalid config: %v", err)
+	} else {
+		config = cfg
+	}
 
-	getopt.Parse()
-	args := getopt.Args()
+	if p, err := lib.LoadPolicy(lib.PolicyPath(config)); err != nil {
+		lib.Fail("Invalid policy: %v", err)
+	} else {
+		policy = p
+	}
 
 	if *helpFlag {
+		lib.Report.Command = "help"
 		usageMessage()
 	} else if *versionFlag {
-		fmt.Printf("Version: %v\n", version)
+		lib.Report.Command = "version"
+		lib.Report.Action = "version"
+		lib.Report.Version = version
+		lib.Report.Data = lib.NewBuildInfo(version, commit, date)
+		fmt.Fprintf(lib.Log.Out, "Version: %v\n", lib.NewBuildInfo(version, commit, date))
+	} else if len(args) == 0 {
+		lib.Report.Command = "switch"
+		switchFromMenu(&client, custBinPath)
 	} else {
-		if len(args) == 0 {
-			helmList, assets := lib.GetAppList(helmURL, &client)
-			recentVersions, _ := lib.GetRecentVersions()     //get recent versions from RECENT file
-			helmList = append(recentVersions, helmList...)   //append recent versions to the top of the list
-			helmList = lib.RemoveDuplicateVersions(helmList) //remove duplicate version
-
-			/* prompt user to select version of helm */
-			prompt := promptui.Select{
-				Label: "Select helm version",
-				Items: helmList,
+		switch args[0] {
+		case "doctor":
+			lib.Report.Command = "doctor"
+			runDoctor(*custBinPath, *fixFlag)
+		case "previous", "-":
+			lib.Report.Command = "previous"
+			switchToPrevious(&client, custBinPath)
+		case "history":
+			lib.Report.Command = "history"
+			runHistory()
+		case "verify":
+			lib.Report.Command = "verify"
+			runVerify(args[1:], *custBinPath, *allFlag)
+		case "notes":
+			lib.Report.Command = "notes"
+			runNotes(args[1:], &client)
+		case "self-update":
+			lib.Report.Command = "self-update"
+			runSelfUpdate()
+		case "outdated":
+			lib.Report.Command = "outdated"
+			runOutdated(args[1:], &client, *custBinPath)
+		case "exec":
+			lib.Report.Command = "exec"
+			runExec(args[1:], *custBinPath)
+		case "env":
+			lib.Report.Command = "env"
+			runEnv(args[1:], *custBinPath)
+		case "plugins":
+			lib.Report.Command = "plugins"
+			runPlugins(args[1:], *custBinPath)
+		case "setup":
+			lib.Report.Command = "setup"
+			runSetup(args[1:], *custBinPath)
+		case "pin":
+			lib.Report.Command = "pin"
+			runPin(args[1:], *custBinPath, pinFormat)
+		case "import":
+			lib.Report.Command = "import"
+			runImport(args[1:], &client, !*noVerifyFlag)
+		default:
+			lib.Report.Command = "switch"
+			switchToVersion(args, &client, custBinPath)
+		}
+	}
+
+	lib.Exit(0)
+}
+
+// parseArgs : parse the options wherever they appear, eg. helmswitch doctor --fix
+// everything after "--" is kept as arguments
+func parseArgs() []string {
+	args := []string{}
+	argv := os.Args
+	getopt.CommandLine.Parse(argv)
+
+	for rest := getopt.Args(); len(rest) > 0; rest = getopt.Args() {
+		if argv[len(argv)-len(rest)-1] == "--" {
+			return append(args, rest...)
+		}
+		args = append(args, rest[0])
+		argv = append([]string{os.Args[0]}, rest[1:]...)
+		getopt.CommandLine.Parse(argv)
+	}
+	return args
+}
+
+func switchFromMenu(client *modal.Client, custBinPath *string) {
+	useVersion(selectFromMenu(client, *custBinPath), client, custBinPath, lib.TriggerMenu)
+}
+
+// selectFromMenu : the version chosen in the menu, among those the charts of the tree work with when nothing is pinned
+func selectFromMenu(client *modal.Client, binPath string) string {
+	custBinPath := &binPath
+	installLocation := storeDir()
+	helmList, assets := getAppList(client)
+	recentVersions, _ := lib.GetRecentVersions(config.History.Recent) //get most used recent versions from history
+
+	state, err := lib.LoadState(installLocation)
+	if err != nil {
+		lib.Report.Warn("%v", err)
+	}
+	cwd, _ := os.Getwd()
+	resolution := lib.ResolveVersion(cwd, "", *custBinPath, installLocation)
+	pinned := ""
+	if resolution.Pinned() {
+		pinned = resolution.Version
+	}
+	active := lib.ActiveVersion(*custBinPath, installLocation)
+
+	items := lib.BuildMenu(helmList, assets, state, recentVersions, active, pinned)
+	for i := range items {
+		if rule, ok := policy.Evaluate(items[i].Version); ok {
+			items[i].Policy = rule.Action
+			items[i].PolicyReason = rule.Reason
+		}
+	}
+	if rule, ok := policy.Evaluate(pinned); ok && rule.Action == lib.PolicyRefuse {
+		lib.Report.Warn("%s %s pinned in %s is refused by policy (%s): %s", rule.Tool, pinned, resolution.Source, rule.Versions, rule.Reason)
+	}
+
+	/* without a pin, only offer the versions the charts of the tree work with */
+	if inference := resolution.Charts; inference != nil {
+		allowed := []lib.MenuItem{}
+		for _, item := range items {
+			if inference.Allows(item.Version) {
+				allowed = append(allowed, item)
 			}
+		}
+		if len(allowed) > 0 && len(allowed) < len(items) {
+			lib.Log.Infof("%s, only listing those versions", inference.Reason)
+			items = allowed
+		}
+	}
+
+	/* prompt user to select version of the tool */
+	name := lib.ActiveTool().Name
+	prompt := promptui.Select{
+		Label:             "Select " + name + " version (type to filter)",
+		Items:             items,
+		Size:              15,
+		CursorPos:         lib.MenuCursor(items),
+		StartInSearchMode: true,
+		Searcher: func(input string, index int) bool {
+			return lib.MatchMenuItem(input, items[index])
+		},
+		Templates: &promptui.SelectTemplates{
+			Label:    "{{ . }}",
+			Active:   "▸ {{ .Group | faint }}  {{ .Version | cyan | bold }}  {{ .Markers | green }}",
+			Inactive: "  {{ .Group | faint }}  {{ .Version }}  {{ .Markers | green }}",
+			Selected: "Selected " + name + " {{ .Version | cyan }}",
+			Details: `
+--------- ` + name + ` {{ .Version }} ----------
+{{ "Release:" | faint }}	{{ .Name }}
+{{ "Published:" | faint }}	{{ .PublishedDate }}
+{{ "Status:" | faint }}	{{ .Markers }}
+{{ "Last used:" | faint }}	{{ .LastUsed }}
+{{- if .PolicyReason }}
+{{ "Policy:" | faint }}	{{ .Policy | red }} {{ .PolicyReason }}
+{{- end }}`,
+		},
+	}
+	if lib.Log.Out == os.Stderr {
+		prompt.Stdout = os.Stderr
+	}
+
+	index, _, errPrompt := prompt.Run()
+
+	if errPrompt != nil {
+		lib.Fail("Prompt failed %v", errPrompt)
+	}
+	return items[index].Version
+}
+
+func switchToVersion(args []string, client *modal.Client, custBinPath *string) {
+	semverRegex := regexp.MustCompile(`\A\d+(\.\d+){2}\z`)
+	if len(args) != 1 || !semverRegex.MatchString(args[0]) {
+		usageMessage()
+		return
+	}
+	useVersion(args[0], client, custBinPath, lib.TriggerArgument)
+}
+
+// useVersion : switch to requestedVersion, downloading it if it is not installed yet
+func useVersion(requestedVersion string, client *modal.Client, custBinPath *string, trigger string) {
+
+	if err := policy.Enforce(requestedVersion, allowInsecure); err != nil {
+		lib.Fail("%v\nPass --allow-insecure to use it anyway", err)
+	}
+
+	//check if version is already downloaded before checking if it exists
+	installLocation := storeDir()
+	tool := lib.ActiveTool()
+	binary := installLocation + tool.Prefix() + requestedVersion
+
+	fileInstalled := lib.CheckFileExist(binary)
+
+	if fileInstalled {
+
+		/* refuse to switch to a binary that changed since it was installed */
+		if _, err := lib.VerifyInstalled(installLocation, requestedVersion); err == lib.ErrNoChecksum {
+			lib.Report.Warn("%s has no recorded checksum, reinstall it to enable verification", requestedVersion)
+		} else if err != nil {
+			lib.Fail("%v\nRefusing to switch, remove %s and run helmswitch %s to reinstall it", err, binary, requestedVersion)
+		}
+
+		/* remove current symlink if exist*/
+		symlinkExist := lib.CheckSymlink(*custBinPath)
+
+		if symlinkExist {
+			lib.RemoveSymlink(*custBinPath)
+		}
+		/* set symlink to desired version */
+		lib.CreateSymlink(binary, *custBinPath)
+		lib.Log.Infof("Switched %s to version %q ", tool.Name, requestedVersion)
+		if err := lib.LinkCompanions(installLocation, requestedVersion, *custBinPath); err != nil {
+			lib.Report.Warn("%v", err)
+		}
+		if err := lib.RecordSwitch(installLocation, requestedVersion, *custBinPath); err != nil {
+			lib.Report.Warn("unable to update state: %v", err)
+		}
+		addHistory(installLocation, requestedVersion, *custBinPath, trigger)
+		syncPlugins(requestedVersion)
+
+		lib.Report.Action = "switched"
+		lib.Report.Version = requestedVersion
+		lib.Report.Path = binary
+	} else {
+		//check if version exist before downloading it
+		lib.Log.Infof("%s not found in install path %s", requestedVersion, installLocation)
+		lib.Log.Infof("Checking if the version exists...")
 
-			_, helmVersion, errPrompt := prompt.Run()
+		helmList, assets := getAppList(client)
+		exist := lib.VersionExist(requestedVersion, helmList)
 
-			if errPrompt != nil {
-				log.Printf("Prompt failed %v\n", errPrompt)
-				os.Exit(1)
+		if exist {
+			installLocation := lib.Install(tool.ReleasesURL(), requestedVersion, assets, custBinPath)
+			addHistory(installLocation, requestedVersion, *custBinPath, trigger) //add to history for faster lookup
+			syncPlugins(requestedVersion)
+		} else {
+			lib.Fail("Not a valid %s version", tool.Name)
+		}
+	}
+}
+
+/* releases fetched once per run, the menu, the install and the notes share them */
+var releaseIndex *lib.ReleaseIndex
+
+// getAppList : list the helm releases, from the cached index while it is fresh
+func getAppList(client *modal.Client) ([]string, []modal.Repo) {
+	if releaseIndex == nil {
+		index, err := lib.LoadReleaseIndex(storeDir())
+		if err == nil && index.Fresh(time.Now()) {
+			releaseIndex = index
+		} else {
+			if err != nil && !os.IsNotExist(err) {
+				lib.Report.Warn("%v", err)
 			}
+			refreshReleases(client)
+		}
+	}
+	return releaseIndex.Versions(), releaseIndex.Releases
+}
+
+// refreshReleases : ask GitHub for the releases and cache them
+func refreshReleases(client *modal.Client) {
+	_, assets := lib.GetAppList(lib.ActiveTool().ReleasesURL(), client)
+	releaseIndex = lib.NewReleaseIndex(assets)
+	if err := releaseIndex.Save(storeDir()); err != nil {
+		lib.Report.Warn("unable to cache releases: %v", err)
+	}
+}
+
+// storeDir : directory holding the installed versions of the tool
+func storeDir() string {
+	return lib.StoreDir()
+}
+
+// switchToPrevious : switch back to the version used before the last switch, like cd -
+func switchToPrevious(client *modal.Client, custBinPath *string) {
+	installLocation := storeDir()
+
+	entries, err := lib.LoadHistory(installLocation)
+	if err != nil {
+		lib.Fail("%v", err)
+	}
+
+	current := lib.ActiveVersion(*custBinPath, installLocation)
+	previous, ok := lib.PreviousVersion(entries, current)
+	if !ok {
+		lib.Fail("No previous %s version in the history", lib.ActiveTool().Name)
+	}
+
+	lib.Log.Infof("Switching back from %q to %q", current, previous)
+	useVersion(previous, client, custBinPath, lib.TriggerPrevious)
+}
+
+// addHistory : record the switch, keeping as many entries as configured
+func addHistory(installLocation string, version string, binPath string, trigger string) {
+	entry := lib.NewHistoryEntry(version, binPath, trigger)
+	if err := lib.AddHistory(installLocation, entry, config.History.Size); err != nil {
+		lib.Report.Warn("unable to update history: %v", err)
+	}
+}
+
+func runHistory() {
+	installLocation := storeDir()
+
+	entries, err := lib.LoadHistory(installLocation)
+	if err != nil {
+		lib.Fail("%v", err)
+	}
+
+	/* newest first */
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		fmt.Fprintf(lib.Log.Out, "%s  %-10s %-9s %s", e.Time.Local().Format("2006-01-02 15:04:05"), e.Version, e.Trigger, e.BinPath)
+		if e.Dir != "" {
+			fmt.Fprintf(lib.Log.Out, "  (from %s)", e.Dir)
+		}
+		fmt.Fprintln(lib.Log.Out)
+	}
+
+	lib.Report.Action = "listed"
+	lib.Report.Data = entries
+}
+
+// verifyResult : outcome of re-hashing one installed version
+type verifyResult struct {
+	Version string `json:"version"`
+	Status  string `json:"status"`
+	SHA256  string `json:"sha256,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+func runVerify(args []string, binPath string, all bool) {
+	installLocation := storeDir()
+
+	versions := args
+	if all {
+		versions = lib.ListInstalledVersions(installLocation)
+	} else if len(versions) == 0 {
+		active := lib.ActiveVersion(binPath, installLocation)
+		if active == "" {
+			lib.Fail("No active %s version at %s, pass a version or --all", lib.ActiveTool().Name, binPath)
+		}
+		versions = []string{active}
+	}
+
+	results := []verifyResult{}
+	failed := false
+	for _, v := range versions {
+		r := verifyResult{Version: v, Status: lib.CheckOK}
+		if !lib.CheckFileExist(installLocation + lib.ActiveTool().Prefix() + v) {
+			r.Status = lib.CheckFail
+			r.Message = "not installed"
+		} else if sum, err := lib.VerifyInstalled(installLocation, v); err == lib.ErrNoChecksum {
+			r.Status = lib.CheckWarn
+			r.SHA256 = sum
+			r.Message = err.Error()
+		} else if err != nil {
+			r.Status = lib.CheckFail
+			r.SHA256 = sum
+			r.Message = err.Error()
+		} else {
+			r.SHA256 = sum
+		}
+
+		if r.Status == lib.CheckFail {
+			failed = true
+		}
+		if r.Message == "" {
+			fmt.Fprintf(lib.Log.Out, "[%-4s] %s %s\n", r.Status, r.Version, r.SHA256)
+		} else {
+			fmt.Fprintf(lib.Log.Out, "[%-4s] %s %s\n", r.Status, r.Version, r.Message)
+		}
+		results = append(results, r)
+	}
+
+	lib.Report.Action = "verified"
+	lib.Report.Data = results
+	if failed {
+		lib.Report.Error = "verification failed"
+		lib.Exit(1)
+	}
+}
+
+// releaseNotes : the notes of one release
+type releaseNotes struct {
+	Version   string    `json:"version"`
+	Name      string    `json:"name"`
+	Published time.Time `json:"published"`
+	URL       string    `json:"url"`
+	Body      string    `json:"body"`
+}
+
+func runNotes(args []string, client *modal.Client) {
+	if len(args) != 1 {
+		usageMessage()
+		lib.Fail("Expecting a version or a range, eg. helmswitch notes 3.1.0..3.3.0")
+	}
+	from, to, err := lib.ParseVersionRange(args[0])
+	if err != nil {
+		lib.Fail("%v", err)
+	}
+
+	/* a version newer than the cache means the cache is out of date */
+	getAppList(client)
+	if _, ok := releaseIndex.Find(to); !ok && time.Since(releaseIndex.FetchedAt) > time.Minute {
+		lib.Log.Verbosef("%s is not in the cached releases, refreshing", to)
+		refreshReleases(client)
+	}
+
+	var releases []modal.Repo
+	if from == "" {
+		if r, ok := releaseIndex.Find(to); ok {
+			releases = []modal.Repo{r}
+		}
+	} else if releases, err = releaseIndex.Between(from, to); err != nil {
+		lib.Fail("%v", err)
+	}
+	if len(releases) == 0 {
+		lib.Fail("No release notes found for %s", args[0])
+	}
+
+	/* colors only make sense on a terminal */
+	color := false
+	if info, err := os.Stdout.Stat(); err == nil && !lib.JSONOutput {
+		color = info.Mode()&os.ModeCharDevice != 0
+	}
+
+	notes := []releaseNotes{}
+	for _, r := range releases {
+		n := releaseNotes{
+			Version:   strings.TrimPrefix(r.TagName, "v"),
+			Name:      r.Name,
+			Published: r.PublishedAt,
+			URL:       r.HTMLURL,
+			Body:      r.Body,
+		}
+		title := n.Name
+		if title == "" {
+			title = "Helm " + r.TagName
+		}
+		header := fmt.Sprintf("# %s (%s)", title, n.Published.Local().Format("2006-01-02"))
+		fmt.Fprintln(lib.Log.Out, lib.RenderMarkdown(header+"\n\n"+n.Body, color))
+		notes = append(notes, n)
+	}
+
+	lib.Report.Action = "listed"
+	lib.Report.Version = to
+	lib.Report.Data = notes
+}
+
+// runOutdated : compare the given, pinned, project, chart or active version with the releases
+func runOutdated(args []string, client *modal.Client, binPath string) {
+	argument := ""
+	if len(args) > 0 {
+		argument = args[0]
+	}
+	cwd, _ := os.Getwd()
+	resolution := lib.ResolveVersion(cwd, argument, binPath, storeDir())
+	if resolution.Version == "" {
+		lib.Fail("No pinned, project or active %s version, pass a version to compare", lib.ActiveTool().Name)
+	}
+	current, source := resolution.Version, resolution.Source
+
+	helmList, _ := getAppList(client)
+	result, err := lib.CheckOutdated(current, helmList)
+	if err != nil {
+		lib.Fail("%v", err)
+	}
+	result.Source = source
+
+	none := func(v string) string {
+		if v == "" {
+			return "up to date"
+		}
+		return v
+	}
+	fmt.Fprintf(lib.Log.Out, "%s %s (%s)\n", lib.ActiveTool().Name, result.Current, result.Source)
+	fmt.Fprintf(lib.Log.Out, "  patch: %s\n", none(result.Patch))
+	fmt.Fprintf(lib.Log.Out, "  minor: %s\n", none(result.Minor))
+	fmt.Fprintf(lib.Log.Out, "  major: %s\n", none(result.Major))
+
+	lib.Report.Action = "compared"
+	lib.Report.Version = result.Current
+	lib.Report.Data = result
+	if result.Patch != "" {
+		lib.Report.Error = fmt.Sprintf("newer patch release %s available", result.Patch)
+		lib.Exit(1)
+	}
+}
+
+// execVersion : the version given first in args or resolved from the current dir, checked against the rule of
+// the kube context, and the remaining args; fails when none of them decides, never switching nor prompting
+func execVersion(args []string, binPath string) (string, []string) {
+	argument := ""
+	if len(args) > 0 && lib.ValidVersionFormat(strings.TrimPrefix(args[0], "v")) {
+		argument, args = args[0], args[1:]
+	}
+	cwd, _ := os.Getwd()
+	resolution := lib.ResolveVersion(cwd, argument, binPath, storeDir())
+	if resolution.From == lib.ResolvedCharts {
+		lib.Log.Infof("Using helm %s: %s", resolution.Version, resolution.Reason)
+	}
+
+	requestedVersion := contextVersion(resolution.Version, resolution.Source, args)
+	if requestedVersion == "" {
+		name := lib.ActiveTool().Name
+		if resolution.Reason != "" {
+			lib.Fail("%s, run helmswitch with one of them or pass the version to use", resolution.Reason)
+		}
+		lib.Fail("No pinned, project or active %s version, run helmswitch to choose one or pass the version to use", name)
+	}
+	return requestedVersion, args
+}
+
+// contextVersion : requestedVersion if the rule of the kube context helm runs against with args allows it,
+// else the newest installed version it allows
+func contextVersion(requestedVersion string, source string, args []string) string {
+	if len(config.Contexts) == 0 {
+		return requestedVersion
+	}
+	context, file, err := lib.SelectedContext(args, lib.KubeconfigFiles())
+	if err != nil {
+		lib.Report.Warn("context rules not applied: %v", err)
+		return requestedVersion
+	}
+	rule, ok := lib.MatchContext(config.Contexts, context)
+	if !ok || (requestedVersion != "" && rule.Check(requestedVersion)) {
+		return requestedVersion
+	}
+
+	name := lib.ActiveTool().Name
+	if source == "argument" {
+		lib.Fail("%s %s cannot be used with context %s (from %s), which requires %s", name, requestedVersion, context, file, rule.Versions)
+	}
+	chosen, ok := lib.NewestMatching(rule, lib.ListInstalledVersions(storeDir()))
+	if !ok {
+		lib.Fail("Context %s (from %s) requires %s %s and none is installed, run helmswitch with one of them", context, file, name, rule.Versions)
+	}
+	if requestedVersion != "" {
+		lib.Log.Infof("Using %s %s instead of %s from %s: context %s requires %s", name, chosen, requestedVersion, source, context, rule.Versions)
+	} else {
+		lib.Log.Infof("Using %s %s: context %s requires %s", name, chosen, context, rule.Versions)
+	}
+	return chosen
+}
+
+// runExec : run an installed version with its isolated homes, eg. helmswitch exec 2.16.9 -- list
+func runExec(args []string, binPath string) {
+	/* stdout belongs to the tool */
+	lib.Log.Out = os.Stderr
+	tool := lib.ActiveTool()
+	requestedVersion, args := execVersion(args, binPath)
+	binary := storeDir() + tool.Prefix() + requestedVersion
+	if !lib.CheckFileExist(binary) {
+		lib.Fail("%s %s is not installed, run helmswitch %s first", tool.Name, requestedVersion, requestedVersion)
+	}
+	if err := policy.Enforce(requestedVersion, allowInsecure); err != nil {
+		lib.Fail("%v\nPass --allow-insecure to use it anyway", err)
+	}
+
+	env, err := lib.HomeEnv(storeDir(), requestedVersion, config.Isolation)
+	if err != nil {
+		lib.Fail("%v", err)
+	}
+
+	lib.Log.Debugf("exec %s %s", binary, strings.Join(args, " "))
+	cmd := exec.Command(binary, args...)
+	cmd.Env = lib.EnvList(os.Environ(), env)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+
+	lib.Report.Action = "executed"
+	lib.Report.Version = requestedVersion
+	lib.Report.Path = binary
+	if err := cmd.Run(); err != nil {
+		/* pass the exit code of the tool on */
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			lib.Report.Error = err.Error()
+			lib.Exit(exitErr.ExitCode())
+		}
+		lib.Fail("%v", err)
+	}
+}
+
+// runEnv : print the exports pointing a version at its isolated homes, for eval "$(helmswitch env)"
+func runEnv(args []string, binPath string) {
+	/* stdout is for the exports only */
+	lib.Log.Out = os.Stderr
+	requestedVersion, _ := execVersion(args, binPath)
+	env, err := lib.HomeEnv(storeDir(), requestedVersion, config.Isolation)
+	if err != nil {
+		lib.Fail("%v", err)
+	}
+	if len(env) == 0 {
+		lib.Log.Verbosef("isolation is %s, %s %s uses the homes of the environment", config.Isolation, lib.ActiveTool().Name, requestedVersion)
+	}
+
+	for _, entry := range lib.EnvList(nil, env) {
+		if lib.JSONOutput {
+			break
+		}
+		parts := strings.SplitN(entry, "=", 2)
+		fmt.Fprintf(os.Stdout, "export %s='%s'\n", parts[0], strings.Replace(parts[1], "'", `'\''`, -1))
+	}
+
+	lib.Report.Action = "listed"
+	lib.Report.Version = requestedVersion
+	lib.Report.Data = env
+}
+
+// wantedPlugins : plugins listed in the config and in the nearest .helmswitch.yaml
+func wantedPlugins() ([]lib.Plugin, error) {
+	cwd, _ := os.Getwd()
+	project, _, err := lib.FindProjectConfig(cwd)
+	if err != nil {
+		return nil, err
+	}
+	return lib.MergePlugins(config.Plugins, project.Plugins)
+}
+
+// syncPlugins : install the listed plugins for the version just switched to
+func syncPlugins(requestedVersion string) {
+	if lib.ActiveTool().Name != "helm" {
+		return
+	}
+	plugins, err := wantedPlugins()
+	if err != nil {
+		lib.Report.Warn("plugins not synced: %v", err)
+		return
+	}
+	if len(plugins) == 0 {
+		return
+	}
+
+	binary := storeDir() + lib.ActiveTool().Prefix() + requestedVersion
+	env, err := lib.HomeEnv(storeDir(), requestedVersion, config.Isolation)
+	if err == nil {
+		_, err = lib.SyncPlugins(binary, requestedVersion, env, plugins)
+	}
+	if err != nil {
+		lib.Report.Warn("%v", err)
+	}
+}
 
-			installLocation := lib.Install(helmURL, helmVersion, assets, custBinPath)
-			lib.AddRecent(helmVersion, installLocation) //add to recent file for faster lookup
-			os.Exit(0)
-
-			fmt.Println(helmList)
-
-		} else if len(args) == 1 {
-			semverRegex := regexp.MustCompile(`\A\d+(\.\d+){2}\z`)
-			if semverRegex.MatchString(args[0]) {
-				requestedVersion := args[0]
-
-				//check if version is already downloaded before checking if it exists
-				/* get current user */
-				usr, errCurr := user.Current()
-				if errCurr != nil {
-					log.Fatal(errCurr)
-				}
-				/* set installation location */
-				installLocation := usr.HomeDir + installPath
-
-				fileInstalled := lib.CheckFileExist(installLocation + installVersion + requestedVersion)
-
-				if fileInstalled {
-
-					/* remove current symlink if exist*/
-					symlinkExist := lib.CheckSymlink(*custBinPath)
-
-					if symlinkExist {
-						lib.RemoveSymlink(*custBinPath)
-					}
-					/* set symlink to desired version */
-					lib.CreateSymlink(installLocation+installVersion+requestedVersion, *custBinPath)
-					fmt.Printf("Switched helm to version %q \n", requestedVersion)
-				} else {
-					//check if version exist before downloading it
-					fmt.Println(requestedVersion + " not found in install path " + installPath)
-					fmt.Println("Checking if the version exists...")
-
-					helmList, assets := lib.GetAppList(helmURL, &client)
-					exist := lib.VersionExist(requestedVersion, helmList)
-
-					if exist {
-						installLocation := lib.Install(helmURL, requestedVersion, assets, custBinPath)
-						lib.AddRecent(requestedVersion, installLocation) //add to recent file for faster lookup
-					} else {
-						fmt.Println("Not a valid helm version")
-					}
-
-				}
-
-			} else {
-				usageMessage()
+// runPlugins : compare the installed plugins with the listed ones, or sync them
+func runPlugins(args []string, binPath string) {
+	tool := lib.ActiveTool()
+	if tool.Name != "helm" {
+		lib.Fail("plugins are managed for helm only")
+	}
+	action := "status"
+	if len(args) > 0 && (args[0] == "status" || args[0] == "sync") {
+		action, args = args[0], args[1:]
+	}
+
+	requestedVersion, _ := execVersion(args, binPath)
+	binary := storeDir() + tool.Prefix() + requestedVersion
+	if !lib.CheckFileExist(binary) {
+		lib.Fail("%s %s is not installed, run helmswitch %s first", tool.Name, requestedVersion, requestedVersion)
+	}
+	plugins, err := wantedPlugins()
+	if err != nil {
+		lib.Fail("%v", err)
+	}
+	env, err := lib.HomeEnv(storeDir(), requestedVersion, config.Isolation)
+	if err != nil {
+		lib.Fail("%v", err)
+	}
+
+	var states []lib.PluginState
+	if action == "sync" {
+		states, err = lib.SyncPlugins(binary, requestedVersion, env, plugins)
+		lib.Report.Action = "synced"
+	} else {
+		var dir string
+		if dir, err = lib.PluginDir(requestedVersion, env); err == nil {
+			states, err = lib.PluginsStatus(dir, requestedVersion, plugins)
+		}
+		lib.Report.Action = "checked"
+	}
+
+	none := func(v string) string {
+		if v == "" {
+			return "-"
+		}
+		return v
+	}
+	fmt.Fprintf(lib.Log.Out, "%-20s %-12s %-14s %-12s %s\n", "PLUGIN", "WANTED", "HELM", "INSTALLED", "STATUS")
+	drift := 0
+	for _, state := range states {
+		fmt.Fprintf(lib.Log.Out, "%-20s %-12s %-14s %-12s %s\n", state.Name, none(state.Wanted), none(state.Helm), none(state.Installed), state.Status)
+		if state.Status == lib.PluginMissing || state.Status == lib.PluginDrift {
+			drift++
+		}
+	}
+
+	lib.Report.Version = requestedVersion
+	lib.Report.Data = states
+	if err != nil {
+		lib.Fail("%v", err)
+	}
+	if drift > 0 {
+		lib.Report.Error = fmt.Sprintf("%d plugins missing or at another version, run helmswitch plugins sync", drift)
+		lib.Log.Errorf("%s", lib.Report.Error)
+		lib.Exit(1)
+	}
+}
+
+// runSetup : write the repositories of .helmswitch.yaml into the repositories.yaml of the version, offline
+func runSetup(args []string, binPath string) {
+	if lib.ActiveTool().Name != "helm" {
+		lib.Fail("setup configures helm only")
+	}
+	cwd, _ := os.Getwd()
+	project, path, err := lib.FindProjectConfig(cwd)
+	if err != nil {
+		lib.Fail("%v", err)
+	}
+	if path == "" {
+		lib.Fail("No .helmswitch.yaml in %s or its parents", cwd)
+	}
+
+	requestedVersion, _ := execVersion(args, binPath)
+	env, err := lib.HomeEnv(storeDir(), requestedVersion, config.Isolation)
+	if err != nil {
+		lib.Fail("%v", err)
+	}
+	file, err := lib.RepositoryFile(requestedVersion, env)
+	if err != nil {
+		lib.Fail("%v", err)
+	}
+
+	lib.Report.Version = requestedVersion
+	lib.Report.Path = file
+	if len(project.Repositories) == 0 {
+		lib.Log.Infof("%s lists no repositories", path)
+		lib.Report.Action = "unchanged"
+		return
+	}
+
+	states, err := lib.SetupRepositories(file, requestedVersion, project.Repositories)
+	if err != nil {
+		lib.Fail("%v", err)
+	}
+	for _, state := range states {
+		fmt.Fprintf(lib.Log.Out, "%-10s %-20s %s\n", state.Status, state.Name, state.URL)
+	}
+	lib.Log.Infof("Repositories of helm %s written to %s, run helm repo update to fetch their indexes", requestedVersion, file)
+
+	lib.Report.Action = "configured"
+	lib.Report.Data = states
+}
+
+// runPin : pin the given or active version in the current dir
+func runPin(args []string, binPath string, format string) {
+	requestedVersion := ""
+	if len(args) > 0 {
+		requestedVersion = strings.TrimPrefix(args[0], "v")
+	} else if active := lib.ActiveVersion(binPath, storeDir()); active != "" {
+		requestedVersion = active
+	} else {
+		lib.Fail("No active %s version, pass the version to pin", lib.ActiveTool().Name)
+	}
+	if err := policy.Enforce(requestedVersion, allowInsecure); err != nil {
+		lib.Fail("%v\nPass --allow-insecure to pin it anyway", err)
+	}
+
+	cwd, _ := os.Getwd()
+	file, err := lib.WritePin(cwd, requestedVersion, format)
+	if err != nil {
+		lib.Fail("%v", err)
+	}
+	lib.Log.Infof("Pinned %s %s in %s", lib.ActiveTool().Name, requestedVersion, file)
+
+	lib.Report.Action = "pinned"
+	lib.Report.Version = requestedVersion
+	lib.Report.Path = file
+}
+
+// runImport : adopt the helm binaries of other version and package managers, or those given, into the store
+func runImport(args []string, client *modal.Client, verify bool) {
+	tool := lib.ActiveTool()
+	if tool.Name != "helm" {
+		lib.Fail("import supports helm only")
+	}
+	installLocation := storeDir()
+
+	var candidates []lib.ImportCandidate
+	if len(args) > 0 {
+		for _, path := range args {
+			candidates = append(candidates, lib.ImportCandidate{Path: path, Source: "argument"})
+		}
+	} else {
+		usr, err := user.Current()
+		if err != nil {
+			lib.Fail("%v", err)
+		}
+		candidates = lib.DiscoverBinaries(lib.ImportSources(usr.HomeDir), installLocation)
+	}
+	if len(candidates) == 0 {
+		lib.Log.Infof("No %s binary found to import", tool.Name)
+	}
+
+	failed := 0
+	for i := range candidates {
+		c := &candidates[i]
+		c.Status = lib.ImportFailed
+
+		version, err := lib.BinaryVersion(c.Path)
+		if err != nil {
+			c.Reason = err.Error()
+			failed++
+			continue
+		}
+		c.Version = version
+		if lib.CheckFileExist(installLocation + tool.Prefix() + version) {
+			c.Status, c.Reason = lib.ImportSkipped, "already installed"
+			continue
+		}
+		if err := policy.Enforce(version, allowInsecure); err != nil {
+			c.Reason = err.Error()
+			failed++
+			continue
+		}
+
+		sourceURL, archiveSHA := "", ""
+		switch {
+		case !verify:
+			c.Reason = "not verified, --no-verify"
+		case c.Unverifiable():
+			c.Reason = "not verified, " + c.Source + " builds " + tool.Name + " from source"
+		default:
+			getAppList(client)
+			release, ok := releaseIndex.Find(version)
+			if !ok {
+				c.Reason = "not verified, " + version + " is not a release of " + tool.Repo
+				break
 			}
+			sourceURL, archiveSHA, err = lib.VerifyBinary(c.Path, version, release, runtime.GOOS, runtime.GOARCH)
+			if err == lib.ErrChecksumMismatch {
+				c.Reason = err.Error()
+				failed++
+				continue
+			}
+			if err != nil {
+				c.Reason = "not verified, " + err.Error()
+				sourceURL, archiveSHA = "", ""
+				break
+			}
+			c.Verified = true
+		}
+
+		if err := lib.AdoptBinary(installLocation, c.Path, version, sourceURL, archiveSHA); err != nil {
+			c.Reason = err.Error()
+			failed++
+			continue
+		}
+		c.Status = lib.ImportAdopted
+	}
 
+	for _, c := range candidates {
+		fmt.Fprintf(lib.Log.Out, "%-8s %-10s %-10s %s", c.Status, c.Version, c.Source, c.Path)
+		if c.Reason != "" {
+			fmt.Fprintf(lib.Log.Out, " (%s)", c.Reason)
 		}
+		fmt.Fprintln(lib.Log.Out)
+	}
 
+	lib.Report.Action = "imported"
+	lib.Report.Data = candidates
+	if failed > 0 {
+		lib.Report.Error = fmt.Sprintf("%d binaries not imported", failed)
+		lib.Log.Errorf("%s", lib.Report.Error)
+		lib.Exit(1)
 	}
+}
 
+// runSelfUpdate : install the latest release of helmswitch over the running binary
+func runSelfUpdate() {
+	release, err := lib.LatestSelfRelease("")
+	if err != nil {
+		lib.Fail("%v", err)
+	}
+
+	latest := strings.TrimPrefix(release.TagName, "v")
+	lib.Report.Version = latest
+	if !lib.SelfUpdateAvailable(version, release) {
+		lib.Log.Infof("helmswitch %s is up to date", version)
+		lib.Report.Action = "up-to-date"
+		return
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		lib.Fail("%v", err)
+	}
+	sum, err := lib.SelfUpdate(release, executable)
+	if err != nil {
+		lib.Fail("%v", err)
+	}
+
+	lib.Log.Infof("Updated helmswitch from %s to %s", version, latest)
+	lib.Report.Action = "updated"
+	lib.Report.Path = executable
+	lib.Report.Checksum = sum
+}
+
+func runDoctor(binPath string, fix bool) {
+	opts := lib.DefaultDoctorOptions(binPath)
+	opts.Fix = fix
+	checks := lib.Doctor(opts)
+
+	failed := false
+	for _, c := range checks {
+		status := c.Status
+		if c.Fixed {
+			status = "fixed"
+		} else if c.Status == lib.CheckFail {
+			failed = true
+		}
+		fmt.Fprintf(lib.Log.Out, "[%-5s] %s: %s\n", status, c.Name, c.Message)
+		if c.Suggestion != "" && !c.Fixed {
+			fmt.Fprintf(lib.Log.Out, "        suggestion: %s\n", c.Suggestion)
+		}
+	}
+
+	lib.Report.Action = "diagnosed"
+	if fix {
+		lib.Report.Action = "fixed"
+	}
+	lib.Report.Data = checks
+	if failed {
+		lib.Report.Error = "doctor found problems"
+		lib.Exit(1)
+	}
 }
 
 func usageMessage() {
-	fmt.Print("\n\n")
+	fmt.Fprint(lib.Log.Out, "\n\n")
 	getopt.PrintUsage(os.Stderr)
-	fmt.Println("Supply the helm version as an argument (ex: helmswitch 2.4.13 ), or choose from a menu")
+	fmt.Fprintln(lib.Log.Out, "Supply the helm version as an argument (ex: helmswitch 2.4.13 ), or choose from a menu")
+	fmt.Fprintln(lib.Log.Out, "Other tools are managed with --tool (ex: helmswitch --tool kubectl 1.18.8 )")
+	fmt.Fprintln(lib.Log.Out, "Tools: "+strings.Join(lib.ToolNames(), ", "))
+	fmt.Fprintln(lib.Log.Out, "Commands:")
+	fmt.Fprintln(lib.Log.Out, "  doctor [--fix]                 diagnose the installation and suggest fixes")
+	fmt.Fprintln(lib.Log.Out, "  verify [version...|--all]      re-hash installed binaries, the active one by default")
+	fmt.Fprintln(lib.Log.Out, "  history                        list previous switches, newest first")
+	fmt.Fprintln(lib.Log.Out, "  previous (or -)                switch back to the version used before the last switch")
+	fmt.Fprintln(lib.Log.Out, "  outdated [version]             list newer patch, minor and major releases, exits 1 when a patch is missing")
+	fmt.Fprintln(lib.Log.Out, "  exec [version] -- args...      run a version, the pinned or active one by default, with its isolated homes")
+	fmt.Fprintln(lib.Log.Out, "  env [version]                  print the exports of the isolated homes, for eval \"$(helmswitch env)\"")
+	fmt.Fprintln(lib.Log.Out, "  plugins [sync] [version]       compare the installed helm plugins with the listed ones, exits 1 on drift")
+	fmt.Fprintln(lib.Log.Out, "  pin [version] [--format f]     pin the version, the active one by default, in .helm-version or .tool-versions")
+	fmt.Fprintln(lib.Log.Out, "  import [path...] [--no-verify] adopt helm binaries of asdf, helmenv, Homebrew or /usr/local/bin/helm-v*")
+	fmt.Fprintln(lib.Log.Out, "  setup [version]                add the repositories of .helmswitch.yaml to the repositories.yaml of helm")
+	fmt.Fprintln(lib.Log.Out, "  self-update                    replace helmswitch with its latest release")
+	fmt.Fprintln(lib.Log.Out, "  notes version|from..to         show the release notes of a version, or of every release after from up to to")
 }